@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"github.com/mfojtik/controller-framework/pkg/events"
 	"github.com/mfojtik/controller-framework/pkg/framework"
+	"github.com/mfojtik/controller-framework/pkg/queue"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	runtime2 "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -18,19 +20,38 @@ type Context struct {
 	queue         workqueue.RateLimitingInterface
 	queueKey      string
 	name          string
+	listers       map[schema.GroupVersionResource]cache.GenericLister
 }
 
 var _ framework.Context = Context{}
+var _ framework.ListerProvider = Context{}
+
+// Option allows to customize the sync context created by New.
+type Option func(*Context)
+
+// WithQueue sets the queue used by the sync context.
+// This allows to use custom queue implementations, like the priority or fair queue from the queue package.
+func WithQueue(workQueue workqueue.RateLimitingInterface) Option {
+	return func(c *Context) {
+		c.queue = workQueue
+	}
+}
 
 // New gives new sync context.
-func New(name string, recorder events.Recorder) framework.Context {
-	return Context{
-		queue: workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{
-			Name: name,
-		}),
+func New(name string, recorder events.Recorder, options ...Option) framework.Context {
+	c := Context{
 		name:          name,
 		eventRecorder: recorder.WithComponentSuffix(strings.ToLower(name)),
 	}
+	for _, option := range options {
+		option(&c)
+	}
+	if c.queue == nil {
+		c.queue = workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{
+			Name: name,
+		})
+	}
+	return c
 }
 
 func NewWithQueueKey(ctx *Context, keyName string) {
@@ -50,12 +71,91 @@ func (c Context) QueueKey() string {
 	return c.queueKey
 }
 
+// WithLister returns copy of the context providing the lister for the resource (see framework.ListerFor).
+func (c Context) WithLister(resource schema.GroupVersionResource, lister cache.GenericLister) Context {
+	listers := make(map[schema.GroupVersionResource]cache.GenericLister, len(c.listers)+1)
+	for r, l := range c.listers {
+		listers[r] = l
+	}
+	listers[resource] = lister
+	c.listers = listers
+	return c
+}
+
+func (c Context) Lister(resource schema.GroupVersionResource) (cache.GenericLister, bool) {
+	lister, ok := c.listers[resource]
+	return lister, ok
+}
+
 func (c Context) Recorder() events.Recorder {
 	return c.eventRecorder
 }
 
 // EventHandler provides default event handler that is added to an informers passed to controller factory.
 func (c Context) EventHandler(queueKeysFunc framework.ObjectQueueKeysFunc, filter framework.EventFilterFunc) cache.ResourceEventHandler {
+	return c.PredicateEventHandler(queueKeysFunc, filter)
+}
+
+// PriorityEventHandler provides event handler that adds the keys to the queue with the given priority.
+// If the queue does not support priorities, the keys are added as usual.
+func (c Context) PriorityEventHandler(queueKeysFunc framework.ObjectQueueKeysFunc, filter framework.EventFilterFunc, priority int) cache.ResourceEventHandler {
+	return c.PredicateEventHandler(queueKeysFunc, filter, WithEnqueuePriority(priority))
+}
+
+// DebouncedEventHandler provides event handler that passes the keys to the debouncer instead of adding them to the queue
+// directly. This coalesces bursts of events for the same key (eg. a rollout updating the same object many times) into a single sync.
+func (c Context) DebouncedEventHandler(queueKeysFunc framework.ObjectQueueKeysFunc, filter framework.EventFilterFunc, debouncer *queue.Debouncer) cache.ResourceEventHandler {
+	return c.PredicateEventHandler(queueKeysFunc, filter, WithDebouncer(debouncer))
+}
+
+// EventHandlerOption customizes how the event handler adds the keys to the queue.
+type EventHandlerOption func(*eventHandlerOptions)
+
+type eventHandlerOptions struct {
+	priority  *int
+	debouncer *queue.Debouncer
+}
+
+// WithEnqueuePriority makes the event handler add the keys with the given priority (see queue.AddWithPriority).
+func WithEnqueuePriority(priority int) EventHandlerOption {
+	return func(o *eventHandlerOptions) {
+		o.priority = &priority
+	}
+}
+
+// WithDebouncer makes the event handler pass the keys to the debouncer. The debouncer is responsible for the priority of the keys.
+func WithDebouncer(debouncer *queue.Debouncer) EventHandlerOption {
+	return func(o *eventHandlerOptions) {
+		o.debouncer = debouncer
+	}
+}
+
+// PredicateEventHandler provides event handler that enqueue the keys only for the events accepted by the predicate.
+// Unlike the filter, the predicate receives both old and new object on update. The nil predicate accepts all events.
+func (c Context) PredicateEventHandler(queueKeysFunc framework.ObjectQueueKeysFunc, predicate framework.Predicate, options ...EventHandlerOption) cache.ResourceEventHandler {
+	o := &eventHandlerOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	enqueueKeys := c.enqueueKeys
+	switch {
+	case o.debouncer != nil:
+		enqueueKeys = func(keys ...string) {
+			for _, key := range keys {
+				o.debouncer.Add(key)
+			}
+		}
+	case o.priority != nil:
+		enqueueKeys = func(keys ...string) {
+			for _, key := range keys {
+				queue.AddWithPriority(c.queue, key, *o.priority)
+			}
+		}
+	}
+	return c.eventHandler(queueKeysFunc, predicate, enqueueKeys)
+}
+
+func (c Context) eventHandler(queueKeysFunc framework.ObjectQueueKeysFunc, predicate framework.Predicate, enqueueKeys func(keys ...string)) cache.ResourceEventHandler {
 	resourceEventHandler := cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			runtimeObj, ok := obj.(runtime.Object)
@@ -63,7 +163,7 @@ func (c Context) EventHandler(queueKeysFunc framework.ObjectQueueKeysFunc, filte
 				runtime2.HandleError(fmt.Errorf("added object %+v is not runtime Object", obj))
 				return
 			}
-			c.enqueueKeys(queueKeysFunc(runtimeObj)...)
+			enqueueKeys(queueKeysFunc(runtimeObj)...)
 		},
 		UpdateFunc: func(old, new interface{}) {
 			runtimeObj, ok := new.(runtime.Object)
@@ -71,28 +171,52 @@ func (c Context) EventHandler(queueKeysFunc framework.ObjectQueueKeysFunc, filte
 				runtime2.HandleError(fmt.Errorf("updated object %+v is not runtime Object", runtimeObj))
 				return
 			}
-			c.enqueueKeys(queueKeysFunc(runtimeObj)...)
+			enqueueKeys(queueKeysFunc(runtimeObj)...)
 		},
 		DeleteFunc: func(obj interface{}) {
 			runtimeObj, ok := obj.(runtime.Object)
 			if !ok {
 				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
-					c.enqueueKeys(queueKeysFunc(tombstone.Obj.(runtime.Object))...)
+					enqueueKeys(queueKeysFunc(tombstone.Obj.(runtime.Object))...)
 
 					return
 				}
 				runtime2.HandleError(fmt.Errorf("updated object %+v is not runtime Object", runtimeObj))
 				return
 			}
-			c.enqueueKeys(queueKeysFunc(runtimeObj)...)
+			enqueueKeys(queueKeysFunc(runtimeObj)...)
 		},
 	}
-	if filter == nil {
+	switch p := predicate.(type) {
+	case nil:
 		return resourceEventHandler
+	case framework.EventFilterFunc:
+		if p == nil {
+			return resourceEventHandler
+		}
+		// the filter keeps the semantic of the filtering event handler, where the update of the object that stopped
+		// matching the filter is handled as delete of the old object
+		return cache.FilteringResourceEventHandler{
+			FilterFunc: p,
+			Handler:    resourceEventHandler,
+		}
 	}
-	return cache.FilteringResourceEventHandler{
-		FilterFunc: filter,
-		Handler:    resourceEventHandler,
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if predicate.Create(obj) {
+				resourceEventHandler.OnAdd(obj, false)
+			}
+		},
+		UpdateFunc: func(old, new interface{}) {
+			if predicate.Update(old, new) {
+				resourceEventHandler.OnUpdate(old, new)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if predicate.Delete(obj) {
+				resourceEventHandler.OnDelete(obj)
+			}
+		},
 	}
 }
 
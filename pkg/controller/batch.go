@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+type batchConfig struct {
+	sync       framework.ControllerBatchSyncFn
+	maxSize    int
+	maxLatency time.Duration
+
+	// batches are collected by a single collector, so the workers waiting for the next batch do not split the keys
+	// among themselves and no worker blocks the others while the batch fills
+	batches chan []interface{}
+}
+
+// WithBatchSync makes the workers call the batch sync function with multiple keys at once instead of calling the sync
+// function for every key. The batch is collected from the first key up to maxSize keys, waiting at most maxLatency
+// for more keys to arrive.
+func WithBatchSync(batchSync framework.ControllerBatchSyncFn, maxSize int, maxLatency time.Duration) Option {
+	return func(c *baseController) {
+		if maxSize < 1 {
+			maxSize = 1
+		}
+		c.batch = &batchConfig{sync: batchSync, maxSize: maxSize, maxLatency: maxLatency, batches: make(chan []interface{})}
+	}
+}
+
+// collectBatches passes the batches of keys from the queue to the workers until the queue is shut down or the context
+// is cancelled. The channel of the batches is closed when this returns.
+func (c *baseController) collectBatches(ctx context.Context) {
+	defer close(c.batch.batches)
+	queue := c.syncContext.Queue()
+
+	items := make(chan interface{})
+	go func() {
+		defer close(items)
+		for {
+			// the keys are not collected while the controller is paused, so they keep their position and priority
+			if !c.waitWhilePaused(ctx) {
+				return
+			}
+			item, quit := queue.Get()
+			if quit {
+				return
+			}
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				queue.Done(item)
+				return
+			}
+		}
+	}()
+
+	for {
+		batch, quit := c.collectBatch(ctx, items)
+		if len(batch) > 0 {
+			select {
+			case c.batch.batches <- batch:
+			case <-ctx.Done():
+				for _, item := range batch {
+					queue.Done(item)
+				}
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// collectBatch returns up to maxSize keys. It blocks until the first key is available and then waits at most maxLatency
+// (measured by the controller clock) for the batch to fill. The second return value is true when no more keys will be
+// available, because the queue is shutting down or the context is cancelled.
+func (c *baseController) collectBatch(ctx context.Context, items <-chan interface{}) ([]interface{}, bool) {
+	var batch []interface{}
+	select {
+	case item, ok := <-items:
+		if !ok {
+			return nil, true
+		}
+		batch = append(batch, item)
+	case <-ctx.Done():
+		return nil, true
+	}
+
+	timer := c.getClock().NewTimer(c.batch.maxLatency)
+	defer timer.Stop()
+	for len(batch) < c.batch.maxSize {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				return batch, true
+			}
+			batch = append(batch, item)
+		case <-timer.C():
+			return batch, false
+		case <-ctx.Done():
+			return batch, true
+		}
+	}
+	return batch, false
+}
+
+// nextBatch returns the next collected batch. The second return value is true when the batches are no longer collected
+// or the workerCtx is cancelled.
+func (c *baseController) nextBatch(workerCtx context.Context) ([]interface{}, bool) {
+	select {
+	case batch, ok := <-c.batch.batches:
+		return batch, !ok
+	case <-workerCtx.Done():
+		return nil, true
+	}
+}
+
+// processNextBatch collects the batch of keys and calls the batch sync function.
+// The failed keys are requeued with rate limiting, the successfully synced keys are forgotten.
+func (c *baseController) processNextBatch(queueCtx, workerCtx context.Context) {
+	items, quit := c.nextBatch(workerCtx)
+	if quit {
+		return
+	}
+	if !c.holdWhilePaused(workerCtx, items...) {
+		return
+	}
+	defer func() {
+		for _, item := range items {
+			c.syncContext.Queue().Done(item)
+		}
+	}()
+
+	keys := make([]string, 0, len(items))
+	// the scheduled runs are not passed to batch sync, but they have to be completed when the key sync succeeds
+	pending := map[string]*pendingScheduledRun{}
+	for _, item := range items {
+		key, ok := item.(string)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("%q controller failed to process key %q (not a string)", c.name, item))
+			continue
+		}
+		if run := c.getPendingScheduledRun(key); run != nil {
+			pending[key] = run
+		}
+		keys = append(keys, key)
+	}
+
+	syncStart := c.getClock().Now()
+	results := c.reconcileBatch(queueCtx, keys)
+	c.observeSyncLatency(c.getClock().Since(syncStart))
+
+	for _, key := range keys {
+		err := results[key]
+		if run, ok := pending[key]; ok && err == nil {
+			c.completePendingScheduledRun(queueCtx, key, run)
+		}
+		c.handleSyncResult(key, err)
+	}
+}
+
+// reconcileBatch wraps the batch sync call like reconcile() wraps the sync call, the sync error handler is called with
+// the result of every key.
+func (c *baseController) reconcileBatch(ctx context.Context, keys []string) map[string]error {
+	results := c.batch.sync(ctx, c.syncContext, keys)
+	for _, key := range keys {
+		c.handleSyncError(results[key])
+	}
+	return results
+}
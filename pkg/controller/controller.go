@@ -15,10 +15,11 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/mfojtik/controller-framework/pkg/framework"
+	"github.com/mfojtik/controller-framework/pkg/queue"
 )
 
 // SyntheticRequeueError can be returned from sync() in case of forcing a sync() retry artificially.
@@ -31,8 +32,22 @@ type baseController struct {
 	sync        func(ctx context.Context, controllerContext framework.Context) error
 	syncContext framework.Context
 
-	resyncEvery     time.Duration
-	resyncSchedules []cron.Schedule
+	resyncEvery        time.Duration
+	resyncJitter       float64
+	resyncInitialDelay time.Duration
+	// resyncKeysFunc returns the keys added to the queue on every periodic resync (full resync)
+	resyncKeysFunc func() []string
+	// resyncSpread spread the keys added by full resync across the resync interval
+	resyncSpread bool
+
+	resyncSchedules []NamedSchedule
+	scheduleStates  []*scheduleState
+	// scheduleStore persists the last run of the schedules
+	scheduleStore framework.ScheduleStore
+
+	// pendingScheduledRuns hold the scheduled runs per queue key that were not yet processed by workers
+	pendingScheduledRuns     map[string]*pendingScheduledRun
+	pendingScheduledRunsLock sync.Mutex
 
 	postStartHooks []framework.PostStartHook
 
@@ -41,6 +56,91 @@ type baseController struct {
 
 	syncPanicHandler framework.ControllerSyncPanicFn
 	syncErrorHandler framework.ControllerSyncErrorFn
+
+	// clock is used to schedule the resync schedules
+	clock clock.WithTicker
+
+	// pause is used to pause and resume the workers
+	pause pauseState
+
+	// batch is set when the controller syncs multiple keys at once
+	batch *batchConfig
+
+	// informerStarters start the informers managed by the controller before waiting for the caches to sync
+	informerStarters []func(stopCh <-chan struct{})
+	// handlerRegistrations are removed from the informers when the controller stops
+	handlerRegistrations []HandlerRegistration
+	// debouncers used by the event handlers are shut down when the controller stops
+	debouncers []*queue.Debouncer
+
+	// workers track the running workers
+	workers           workerPool
+	autoscalingPolicy *framework.WorkerAutoscalingPolicy
+}
+
+// Option allows to configure optional controller features.
+type Option func(*baseController)
+
+// WithClock sets the clock used to trigger scheduled runs and periodic resyncs. This allows to test them deterministically.
+func WithClock(clock clock.WithTicker) Option {
+	return func(c *baseController) {
+		c.clock = clock
+	}
+}
+
+// WithSchedules adds the named resync schedules. When the schedule fires, the key returned by its QueueKeyFunc is added
+// to the queue and the Sync() context carries the schedule name and the planned fire time (see framework.ScheduledRunFromContext).
+func WithSchedules(schedules ...NamedSchedule) Option {
+	return func(c *baseController) {
+		c.resyncSchedules = append(c.resyncSchedules, schedules...)
+	}
+}
+
+// WithScheduleStore sets the store used to persist the last run of the resync schedules.
+// The store allows to detect runs missed while the process was down (see framework.MissedRunPolicy).
+func WithScheduleStore(store framework.ScheduleStore) Option {
+	return func(c *baseController) {
+		c.scheduleStore = store
+	}
+}
+
+// WithResyncJitter randomizes the periodic resync interval, each resync is delayed by up to jitterFactor*interval.
+// This prevents the resyncs of multiple controllers to line up.
+func WithResyncJitter(jitterFactor float64) Option {
+	return func(c *baseController) {
+		c.resyncJitter = jitterFactor
+	}
+}
+
+// WithResyncKeysFunc enables full periodic resync. On every periodic resync, all keys returned by the function are
+// added to the queue instead of the DefaultQueueKey.
+func WithResyncKeysFunc(keysFunc func() []string) Option {
+	return func(c *baseController) {
+		c.resyncKeysFunc = keysFunc
+	}
+}
+
+// WithResyncSpread spreads the keys added by the full periodic resync evenly across the resync interval instead of
+// adding all of them at once. This smooths the load caused by the resync of large number of objects.
+func WithResyncSpread() Option {
+	return func(c *baseController) {
+		c.resyncSpread = true
+	}
+}
+
+// WithResyncInitialDelay delays the first periodic resync.
+func WithResyncInitialDelay(delay time.Duration) Option {
+	return func(c *baseController) {
+		c.resyncInitialDelay = delay
+	}
+}
+
+// WithInformerStarters registers functions that start the informers (eg. informer factory Start()) when the controller is started.
+// The informers are stopped when the context passed to Run() is cancelled.
+func WithInformerStarters(starters ...func(stopCh <-chan struct{})) Option {
+	return func(c *baseController) {
+		c.informerStarters = append(c.informerStarters, starters...)
+	}
 }
 
 func New(
@@ -51,31 +151,45 @@ func New(
 	resyncSchedules []cron.Schedule,
 	postStartHooks []framework.PostStartHook,
 	cachesToSync []cache.InformerSynced,
-	cacheSyncTimeout time.Duration) framework.Controller {
-	return &baseController{name: name, informerSynced: cachesToSync, sync: sync, syncContext: syncContext, resyncEvery: resyncEvery, resyncSchedules: resyncSchedules, postStartHooks: postStartHooks, informerSyncedTimeout: cacheSyncTimeout}
+	cacheSyncTimeout time.Duration,
+	options ...Option) framework.Controller {
+	c := &baseController{name: name, informerSynced: cachesToSync, sync: sync, syncContext: syncContext, resyncEvery: resyncEvery, postStartHooks: postStartHooks, informerSyncedTimeout: cacheSyncTimeout, clock: clock.RealClock{}}
+	for _, schedule := range resyncSchedules {
+		c.resyncSchedules = append(c.resyncSchedules, NamedSchedule{Schedule: schedule})
+	}
+	for _, option := range options {
+		option(c)
+	}
+	for i := range c.resyncSchedules {
+		if len(c.resyncSchedules[i].Name) == 0 {
+			c.resyncSchedules[i].Name = fmt.Sprintf("schedule-%d", i)
+		}
+		c.scheduleStates = append(c.scheduleStates, &scheduleState{NamedSchedule: c.resyncSchedules[i]})
+	}
+	return c
 }
 
 var _ framework.Controller = &baseController{}
+var _ framework.PeriodicResyncer = &baseController{}
 
 func (c *baseController) Name() string {
 	return c.name
 }
 
-type scheduledJob struct {
-	queue workqueue.RateLimitingInterface
-	name  string
+func (c *baseController) ResyncInterval() time.Duration {
+	return c.resyncEvery
 }
 
-func newScheduledJob(name string, queue workqueue.RateLimitingInterface) cron.Job {
-	return &scheduledJob{
-		queue: queue,
-		name:  name,
-	}
+func (c *baseController) SetResyncInitialDelay(delay time.Duration) {
+	c.resyncInitialDelay = delay
 }
 
-func (s *scheduledJob) Run() {
-	klog.V(4).Infof("Triggering scheduled %q controller run", s.name)
-	s.queue.Add(framework.DefaultQueueKey)
+// effectiveResyncInterval returns the average interval between periodic resyncs including the jitter.
+func (c *baseController) effectiveResyncInterval() time.Duration {
+	if c.resyncJitter <= 0 {
+		return c.resyncEvery
+	}
+	return c.resyncEvery + time.Duration(float64(c.resyncEvery)*c.resyncJitter/2)
 }
 
 func waitForNamedCacheSync(controllerName string, stopCh <-chan struct{}, cacheSyncs ...cache.InformerSynced) error {
@@ -104,7 +218,15 @@ func (c *baseController) Run(ctx context.Context, workers int) {
 		}
 	})
 
-	// give caches 10 minutes to sync
+	// the event handlers are removed on every return path, the shutdown removes them before the queue is shut down
+	defer c.shutDownDebouncers()
+	defer c.removeEventHandlers()
+
+	for _, start := range c.informerStarters {
+		start(ctx.Done())
+	}
+
+	// give caches the cache sync timeout (10 minutes by default) to sync
 	cacheSyncCtx, cacheSyncCancel := context.WithTimeout(ctx, c.informerSyncedTimeout)
 	defer cacheSyncCancel()
 	err := waitForNamedCacheSync(c.name, cacheSyncCtx.Done(), c.informerSynced...)
@@ -114,14 +236,20 @@ func (c *baseController) Run(ctx context.Context, workers int) {
 			// Exit gracefully because the controller was requested to stop.
 			return
 		default:
-			// If caches did not sync after 10 minutes, it has taken oddly long and
+			if framework.ReturnOnCacheSyncTimeout(ctx) {
+				// the caller restarts the controller, other controllers running in the process are not affected
+				utilruntime.HandleError(fmt.Errorf("controller %q stopped: %v", c.name, err))
+				return
+			}
+			// If caches did not sync within the timeout, it has taken oddly long and
 			// we should provide feedback. Since the control loops will never start,
 			// it is safer to exit with a good message than to continue with a dead loop.
-			// TODO: Consider making this behavior configurable.
 			klog.Exit(err)
 		}
 	}
 
+	c.reportPausedMetric(c.Paused())
+
 	var workerWg sync.WaitGroup
 	defer func() {
 		defer klog.Infof("All %s workers have been terminated", c.name)
@@ -131,39 +259,60 @@ func (c *baseController) Run(ctx context.Context, workers int) {
 	// queueContext is used to track and initiate queue shutdown
 	queueContext, queueContextCancel := context.WithCancel(context.TODO())
 
-	for i := 1; i <= workers; i++ {
-		klog.Infof("Starting worker #%d for controller %s  ...", i, c.name)
+	workerCount := 0
+	c.startWorkers(workers, func(stopCh <-chan struct{}) {
+		workerCount++
+		klog.Infof("Starting worker #%d for controller %s  ...", workerCount, c.name)
 		workerWg.Add(1)
 		go func() {
 			defer func() {
 				klog.Infof("Shutting down worker of %s controller ...", c.name)
 				workerWg.Done()
 			}()
-			c.runWorker(queueContext)
+			c.runStoppableWorker(queueContext, stopCh)
+		}()
+	})
+
+	if c.autoscalingPolicy != nil {
+		workerWg.Add(1)
+		go func() {
+			defer workerWg.Done()
+			c.runWorkerAutoscaler(ctx, *c.autoscalingPolicy)
 		}()
 	}
 
-	// if scheduled run is requested, run the cron scheduler
-	if c.resyncSchedules != nil {
-		scheduler := cron.New()
-		for _, s := range c.resyncSchedules {
-			scheduler.Schedule(s, newScheduledJob(c.name, c.syncContext.Queue()))
-		}
-		scheduler.Start()
-		defer scheduler.Stop()
+	// if scheduled run is requested, run the cron schedules
+	for i := range c.scheduleStates {
+		workerWg.Add(1)
+		go func(schedule *scheduleState) {
+			defer workerWg.Done()
+			c.runSchedule(ctx, schedule)
+		}(c.scheduleStates[i])
+	}
+
+	if c.batch != nil {
+		workerWg.Add(1)
+		go func() {
+			defer workerWg.Done()
+			c.collectBatches(queueContext)
+		}()
 	}
 
 	// runPeriodicalResync is independent from queue
 	if c.resyncEvery > 0 {
 		workerWg.Add(1)
-		if c.resyncEvery < 60*time.Second {
+		if effectiveInterval := c.effectiveResyncInterval(); effectiveInterval < 60*time.Second {
 			// Warn about too fast resyncs as they might drain the operators QPS.
 			// This event is cheap as it is only emitted on operator startup.
-			c.syncContext.Recorder().Warningf("FastControllerResync", "Controller %q resync interval is set to %s which might lead to client request throttling", c.name, c.resyncEvery)
+			if c.resyncJitter > 0 {
+				c.syncContext.Recorder().Warningf("FastControllerResync", "Controller %q resync interval is set to %s (%s on average with jitter factor %.2f) which might lead to client request throttling", c.name, c.resyncEvery, effectiveInterval, c.resyncJitter)
+			} else {
+				c.syncContext.Recorder().Warningf("FastControllerResync", "Controller %q resync interval is set to %s which might lead to client request throttling", c.name, c.resyncEvery)
+			}
 		}
 		go func() {
 			defer workerWg.Done()
-			wait.UntilWithContext(ctx, func(ctx context.Context) { c.syncContext.Queue().Add(framework.DefaultQueueKey) }, c.resyncEvery)
+			c.runPeriodicResync(ctx)
 		}()
 	}
 
@@ -188,7 +337,10 @@ func (c *baseController) Run(ctx context.Context, workers int) {
 	// Handle controller shutdown
 
 	<-ctx.Done()                     // wait for controller context to be cancelled
+	c.removeEventHandlers()          // stop the informers from adding keys to the queue
+	c.shutDownDebouncers()           // stop the timers of the debounced keys
 	c.syncContext.Queue().ShutDown() // shutdown the controller queue first
+	c.stopWorkers()                  // prevent workers from being added
 	queueContextCancel()             // cancel the queue context, which tell workers to initiate shutdown
 
 	// Wait for all workers to finish their job.
@@ -197,6 +349,59 @@ func (c *baseController) Run(ctx context.Context, workers int) {
 	klog.Infof("Shutting down %s ...", c.name)
 }
 
+// runPeriodicResync resyncs the controller every resync interval (with jitter) measured by the controller clock until
+// the context is cancelled. The first resync happens after the initial delay.
+func (c *baseController) runPeriodicResync(ctx context.Context) {
+	delay := c.resyncInitialDelay
+	for {
+		if delay > 0 {
+			timer := c.getClock().NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		c.periodicResync()
+
+		delay = c.resyncEvery
+		if c.resyncJitter > 0 {
+			delay = wait.Jitter(c.resyncEvery, c.resyncJitter)
+		}
+	}
+}
+
+// periodicResync adds the DefaultQueueKey or, in case of full resync, all resync keys to the queue.
+// The keys are added with low priority when the controller uses priority queue.
+func (c *baseController) periodicResync() {
+	if c.resyncKeysFunc == nil {
+		queue.AddWithPriority(c.syncContext.Queue(), framework.DefaultQueueKey, queue.PriorityLow)
+		return
+	}
+	keys := c.resyncKeysFunc()
+	klog.V(4).Infof("Full resync of %s controller adds %d keys", c.name, len(keys))
+	for i, key := range keys {
+		if !c.resyncSpread || i == 0 {
+			queue.AddWithPriority(c.syncContext.Queue(), key, queue.PriorityLow)
+			continue
+		}
+		queue.AddAfterWithPriority(c.syncContext.Queue(), key, queue.PriorityLow, time.Duration(int64(c.resyncEvery)*int64(i)/int64(len(keys))))
+	}
+}
+
+func (c *baseController) getClock() clock.WithTicker {
+	if c.clock == nil {
+		return clock.RealClock{}
+	}
+	return c.clock
+}
+
 func (c *baseController) Sync(ctx context.Context, syncCtx framework.Context) error {
 	return c.sync(ctx, syncCtx)
 }
@@ -205,9 +410,26 @@ func (c *baseController) Sync(ctx context.Context, syncCtx framework.Context) er
 // The worker is asked to terminate when the passed context is cancelled and is given terminationGraceDuration time
 // to complete its shutdown.
 func (c *baseController) runWorker(queueCtx context.Context) {
+	c.runStoppableWorker(queueCtx, nil)
+}
+
+// runStoppableWorker runs a single worker that also terminates when the stop channel is closed.
+// The worker finishes the item it is processing before it terminates.
+func (c *baseController) runStoppableWorker(queueCtx context.Context, stopCh <-chan struct{}) {
+	workerCtx, workerCancel := context.WithCancel(queueCtx)
+	defer workerCancel()
+	if stopCh != nil {
+		go func() {
+			select {
+			case <-stopCh:
+				workerCancel()
+			case <-workerCtx.Done():
+			}
+		}()
+	}
 	wait.UntilWithContext(
-		queueCtx,
-		func(queueCtx context.Context) {
+		workerCtx,
+		func(workerCtx context.Context) {
 			defer utilruntime.HandleCrash(func(in interface{}) {
 				if c.syncPanicHandler == nil {
 					panic(in)
@@ -218,10 +440,18 @@ func (c *baseController) runWorker(queueCtx context.Context) {
 			})
 			for {
 				select {
-				case <-queueCtx.Done():
+				case <-workerCtx.Done():
 					return
 				default:
-					c.processNextWorkItem(queueCtx)
+					// the paused workers do not take the keys from the queue, so the keys keep their position and priority
+					if !c.waitWhilePaused(workerCtx) {
+						return
+					}
+					if c.batch != nil {
+						c.processNextBatch(queueCtx, workerCtx)
+					} else {
+						c.processNextWorkItem(queueCtx, workerCtx)
+					}
 				}
 			}
 		},
@@ -231,22 +461,30 @@ func (c *baseController) runWorker(queueCtx context.Context) {
 // reconcile wraps the sync() call and if operator client is set, it handle the degraded condition if sync() returns an error.
 func (c *baseController) reconcile(ctx context.Context, syncCtx framework.Context) error {
 	err := c.sync(ctx, syncCtx)
-	if errors.Is(err, SyntheticRequeueError) {
-		return err
+	c.handleSyncError(err)
+	return err
+}
+
+// handleSyncError passes the result of the sync to the sync error handler. The synthetic requeue errors are not handled.
+func (c *baseController) handleSyncError(err error) {
+	if errors.Is(err, SyntheticRequeueError) || c.syncErrorHandler == nil {
+		return
 	}
-	if c.syncErrorHandler != nil {
-		if handlerErr := c.syncErrorHandler(err); handlerErr != nil {
-			panic(handlerErr)
-		}
+	if handlerErr := c.syncErrorHandler(err); handlerErr != nil {
+		panic(handlerErr)
 	}
-	return err
 }
 
-func (c *baseController) processNextWorkItem(queueCtx context.Context) {
+// processNextWorkItem syncs the next key from the queue. When the controller was paused while the worker waited for the key,
+// the worker holds the key until the controller is resumed or the workerCtx is cancelled.
+func (c *baseController) processNextWorkItem(queueCtx, workerCtx context.Context) {
 	key, quit := c.syncContext.Queue().Get()
 	if quit {
 		return
 	}
+	if !c.holdWhilePaused(workerCtx, key) {
+		return
+	}
 	defer c.syncContext.Queue().Done(key)
 
 	stringKey, ok := key.(string)
@@ -254,7 +492,24 @@ func (c *baseController) processNextWorkItem(queueCtx context.Context) {
 		utilruntime.HandleError(fmt.Errorf("%q controller failed to process key %q (not a string)", c.name, key))
 	}
 
-	if err := c.reconcile(queueCtx, c.syncContext.WithQueueKey(stringKey)); err != nil {
+	syncCtx := queueCtx
+	pending := c.getPendingScheduledRun(stringKey)
+	if pending != nil {
+		syncCtx = framework.WithScheduledRun(queueCtx, pending.run)
+	}
+
+	syncStart := c.getClock().Now()
+	err := c.reconcile(syncCtx, c.syncContext.WithQueueKey(stringKey))
+	c.observeSyncLatency(c.getClock().Since(syncStart))
+	if err == nil && pending != nil {
+		c.completePendingScheduledRun(queueCtx, stringKey, pending)
+	}
+	c.handleSyncResult(key, err)
+}
+
+// handleSyncResult requeues the key with rate limiting when the sync failed and forgets it otherwise.
+func (c *baseController) handleSyncResult(key interface{}, err error) {
+	if err != nil {
 		if errors.Is(err, SyntheticRequeueError) {
 			// logging this helps detecting wedged controllers with missing pre-requirements
 			klog.V(5).Infof("%q controller requested synthetic requeue with key %q", c.name, key)
@@ -12,12 +12,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/robfig/cron"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
+	clocktesting "k8s.io/utils/clock/testing"
 	//"github.com/mfojtik/controller-framework/pkg/operator/v1helpers"
 	//operatorv1 "github.com/openshift/api/operator/v1"
 
 	"github.com/mfojtik/controller-framework/pkg/events/eventstesting"
 	"github.com/mfojtik/controller-framework/pkg/framework"
+	"github.com/mfojtik/controller-framework/pkg/queue"
+	"github.com/mfojtik/controller-framework/pkg/schedulestore"
 )
 
 type fakeInformer struct {
@@ -25,14 +30,28 @@ type fakeInformer struct {
 	eventHandler         cache.ResourceEventHandler
 	addEventHandlerCount int
 	hasSyncedCount       int
+	removedHandlers      int
 	sync.Mutex
 }
 
+type fakeRegistration struct{}
+
+func (fakeRegistration) HasSynced() bool {
+	return true
+}
+
+func (f *fakeInformer) RemoveEventHandler(handle cache.ResourceEventHandlerRegistration) error {
+	f.Lock()
+	defer f.Unlock()
+	f.removedHandlers++
+	return nil
+}
+
 func (f *fakeInformer) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
 	f.Lock()
 	defer func() { f.addEventHandlerCount++; f.Unlock() }()
 	f.eventHandler = handler
-	return nil, nil
+	return fakeRegistration{}, nil
 }
 
 func (f *fakeInformer) HasSynced() bool {
@@ -74,6 +93,32 @@ func TestBaseController_ExitOneIfCachesWontSync(t *testing.T) {
 	}
 }
 
+func TestBaseController_ReturnOnCacheSyncTimeout(t *testing.T) {
+	c := &baseController{
+		name:                  "test",
+		syncContext:           context2.New("test", eventstesting.NewTestingEventRecorder(t)),
+		informerSyncedTimeout: 100 * time.Millisecond,
+		informerSynced: []cache.InformerSynced{
+			func() bool {
+				return false
+			},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(framework.WithReturnOnCacheSyncTimeout(ctx), 1)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected controller to return when caches did not sync")
+	}
+}
+
 func TestBaseController_ReturnOnGracefulShutdownWhileWaitingForCachesToSync(t *testing.T) {
 	c := &baseController{
 		syncContext:           context2.New("test", eventstesting.NewTestingEventRecorder(t)),
@@ -201,3 +246,591 @@ func TestBaseController_Run(t *testing.T) {
 		t.Errorf("expected the post start hook to be terminated when context is cancelled")
 	}
 }
+
+func TestBaseController_FullResyncSpread(t *testing.T) {
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	defer syncContext.Queue().ShutDown()
+	c := &baseController{
+		name:        "TestController",
+		syncContext: syncContext,
+		resyncEvery: time.Hour,
+	}
+	WithResyncKeysFunc(func() []string { return []string{"a", "b", "c"} })(c)
+
+	c.periodicResync()
+	if l := syncContext.Queue().Len(); l != 3 {
+		t.Errorf("expected all keys to be added at once, got %d", l)
+	}
+	for syncContext.Queue().Len() > 0 {
+		key, _ := syncContext.Queue().Get()
+		syncContext.Queue().Done(key)
+	}
+
+	WithResyncSpread()(c)
+	c.periodicResync()
+	if l := syncContext.Queue().Len(); l != 1 {
+		t.Errorf("expected only the first key to be added immediately, got %d", l)
+	}
+}
+
+func TestBaseController_PeriodicResyncWithFakeClock(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	synced := make(chan string, 10)
+	c := New("TestController", func(ctx context.Context, controllerContext framework.Context) error {
+		synced <- controllerContext.QueueKey()
+		return nil
+	}, syncContext, 10*time.Minute, nil, nil, nil, time.Minute, WithClock(fakeClock)).(*baseController)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 1)
+
+	expectResync := func() {
+		t.Helper()
+		select {
+		case key := <-synced:
+			if key != framework.DefaultQueueKey {
+				t.Errorf("expected resync of %q, got %q", framework.DefaultQueueKey, key)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("expected resync")
+		}
+	}
+	waitForResyncTimer := func() {
+		t.Helper()
+		if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+			return fakeClock.HasWaiters(), nil
+		}); err != nil {
+			t.Fatal("expected the resync to wait for the clock")
+		}
+	}
+
+	// the first resync is immediate, the next one happens after the interval passes on the controller clock
+	expectResync()
+	waitForResyncTimer()
+	fakeClock.Step(9 * time.Minute)
+	select {
+	case key := <-synced:
+		t.Fatalf("expected no resync before the interval, got %q", key)
+	case <-time.After(100 * time.Millisecond):
+	}
+	fakeClock.Step(time.Minute)
+	expectResync()
+	waitForResyncTimer()
+}
+
+func mustParseSchedule(t *testing.T, spec string) cron.Schedule {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schedule
+}
+
+func TestLatestRunBefore(t *testing.T) {
+	// the runs of the spec schedule are at the full hour, the runs of the constant delay schedule are counted from the last run
+	hourly := mustParseSchedule(t, "0 0 * * * *")
+	now := time.Date(2023, 7, 1, 3, 30, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		schedule cron.Schedule
+		after    time.Time
+		expected time.Time
+	}{
+		{name: "several missed runs", schedule: hourly, after: now.Add(-210 * time.Minute), expected: now.Add(-30 * time.Minute)},
+		{name: "single missed run", schedule: hourly, after: now.Add(-60 * time.Minute), expected: now.Add(-30 * time.Minute)},
+		{name: "no missed run", schedule: hourly, after: now.Add(-20 * time.Minute)},
+		{name: "several missed constant delay runs", schedule: cron.Every(time.Hour), after: now.Add(-220 * time.Minute), expected: now.Add(-40 * time.Minute)},
+		{name: "constant delay run at the until time", schedule: cron.Every(time.Hour), after: now.Add(-120 * time.Minute), expected: now.Add(-60 * time.Minute)},
+		{name: "long outage of frequent schedule", schedule: cron.Every(time.Second), after: now.Add(-365 * 24 * time.Hour), expected: now.Add(-time.Second)},
+		{name: "long outage of frequent spec schedule", schedule: mustParseSchedule(t, "* * * * * *"), after: now.Add(-365 * 24 * time.Hour), expected: now.Add(-time.Second)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if latest := latestRunBefore(test.schedule, test.after, now); !latest.Equal(test.expected) {
+				t.Errorf("expected latest run %s, got %s", test.expected, latest)
+			}
+		})
+	}
+}
+
+func TestBaseController_PauseResume(t *testing.T) {
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	synced := make(chan string, 10)
+	c := New("TestController", func(ctx context.Context, controllerContext framework.Context) error {
+		synced <- controllerContext.QueueKey()
+		return nil
+	}, syncContext, 0, nil, nil, nil, time.Minute).(*baseController)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Pause()
+	if !c.Paused() {
+		t.Fatal("expected controller to be paused")
+	}
+	go c.Run(ctx, 2)
+
+	syncContext.Queue().Add("foo")
+	select {
+	case key := <-synced:
+		t.Fatalf("expected no sync while paused, got %q", key)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if l := syncContext.Queue().Len(); l != 1 {
+		t.Errorf("expected the key to stay in queue while paused, got %d keys", l)
+	}
+
+	c.Resume()
+	select {
+	case key := <-synced:
+		if key != "foo" {
+			t.Errorf("expected foo to be synced, got %q", key)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected sync after resume")
+	}
+}
+
+func TestBaseController_PauseRunning(t *testing.T) {
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	synced := make(chan string, 10)
+	c := New("TestController", func(ctx context.Context, controllerContext framework.Context) error {
+		synced <- controllerContext.QueueKey()
+		return nil
+	}, syncContext, 0, nil, nil, nil, time.Minute).(*baseController)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 2)
+
+	// make sure the workers run and wait for the next key
+	syncContext.Queue().Add("foo")
+	select {
+	case <-synced:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected sync of foo")
+	}
+
+	c.Pause()
+	syncContext.Queue().Add("bar")
+	select {
+	case key := <-synced:
+		t.Fatalf("expected no sync while paused, got %q", key)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	c.Resume()
+	select {
+	case key := <-synced:
+		if key != "bar" {
+			t.Errorf("expected bar to be synced, got %q", key)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected sync after resume")
+	}
+}
+
+func TestBaseController_PauseKeepsPriority(t *testing.T) {
+	priorityQueue := queue.NewPriorityQueue(queue.PriorityQueueConfig{Name: "TestController"})
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t), context2.WithQueue(priorityQueue))
+	synced := make(chan string, 10)
+	c := New("TestController", func(ctx context.Context, controllerContext framework.Context) error {
+		synced <- controllerContext.QueueKey()
+		return nil
+	}, syncContext, 0, nil, nil, nil, time.Minute).(*baseController)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Pause()
+	go c.Run(ctx, 1)
+
+	priorityQueue.AddWithPriority("low", queue.PriorityLow)
+	select {
+	case key := <-synced:
+		t.Fatalf("expected no sync while paused, got %q", key)
+	case <-time.After(200 * time.Millisecond):
+	}
+	// the paused worker did not take the low priority key, so the high priority key added later is synced first
+	priorityQueue.AddWithPriority("high", queue.PriorityHigh)
+	if l := priorityQueue.Len(); l != 2 {
+		t.Fatalf("expected both keys to stay in queue while paused, got %d keys", l)
+	}
+
+	c.Resume()
+	for _, expected := range []string{"high", "low"} {
+		select {
+		case key := <-synced:
+			if key != expected {
+				t.Errorf("expected %q to be synced, got %q", expected, key)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected sync of %q after resume", expected)
+		}
+	}
+}
+
+func TestBaseController_ScheduledRunRetry(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Date(2023, 7, 1, 2, 59, 0, 0, time.UTC))
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	type syncCall struct {
+		run       framework.ScheduledRun
+		scheduled bool
+	}
+	calls := make(chan syncCall, 10)
+	attempts := 0
+	store := schedulestore.NewInMemoryStore()
+	// retry is closed when the test checked the failed run was not recorded
+	retry := make(chan struct{})
+	c := New("TestController", func(ctx context.Context, controllerContext framework.Context) error {
+		run, scheduled := framework.ScheduledRunFromContext(ctx)
+		calls <- syncCall{run: run, scheduled: scheduled}
+		attempts++
+		switch attempts {
+		case 1:
+			return fmt.Errorf("first sync fails")
+		case 2:
+			<-retry
+		}
+		return nil
+	}, syncContext, 0, nil, nil, nil, time.Minute,
+		WithClock(fakeClock),
+		WithScheduleStore(store),
+		WithSchedules(NamedSchedule{Name: "every-minute", Schedule: cron.Every(time.Minute)}),
+	).(*baseController)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 1)
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatal("expected the schedule to wait for the clock")
+	}
+	fakeClock.Step(time.Minute)
+
+	expectCall := func(scheduled bool) syncCall {
+		t.Helper()
+		select {
+		case call := <-calls:
+			if call.scheduled != scheduled {
+				t.Fatalf("expected scheduled run in context to be %v, got %#v", scheduled, call)
+			}
+			return call
+		case <-time.After(10 * time.Second):
+			t.Fatal("expected sync")
+			return syncCall{}
+		}
+	}
+	first := expectCall(true)
+	second := expectCall(true)
+	if !second.run.PlannedTime.Equal(first.run.PlannedTime) || second.run.Name != "every-minute" {
+		t.Errorf("expected the retry to get the same scheduled run, got %#v and %#v", first.run, second.run)
+	}
+	if lastRun, _ := store.LastRun(context.TODO(), "TestController", "every-minute"); !lastRun.IsZero() {
+		t.Errorf("expected the failed run to not be recorded, got %s", lastRun)
+	}
+	close(retry)
+	// the run is recorded after the retry succeeds
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		lastRun, err := store.LastRun(context.TODO(), "TestController", "every-minute")
+		return lastRun.Equal(first.run.PlannedTime), err
+	}); err != nil {
+		t.Errorf("expected the last run to be recorded after the successful sync: %v", err)
+	}
+
+	// the run is forgotten after the successful sync
+	syncContext.Queue().Add(framework.DefaultQueueKey)
+	expectCall(false)
+}
+
+func TestBaseController_BatchSync(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	batches := make(chan []string, 10)
+	c := New("TestController", nil, syncContext, 0, nil, nil, nil, time.Minute,
+		WithClock(fakeClock),
+		WithBatchSync(func(ctx context.Context, controllerContext framework.Context, keys []string) map[string]error {
+			batches <- keys
+			return map[string]error{"b": fmt.Errorf("sync of b failed")}
+		}, 10, time.Hour),
+	).(*baseController)
+	var lock sync.Mutex
+	var errsHandled []error
+	c.syncErrorHandler = func(err error) error {
+		lock.Lock()
+		defer lock.Unlock()
+		errsHandled = append(errsHandled, err)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 2)
+
+	// the batch waits for more keys until the max latency passes on the controller clock
+	syncContext.Queue().Add("a")
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatal("expected the batch to wait for the clock")
+	}
+	syncContext.Queue().Add("b")
+	select {
+	case keys := <-batches:
+		t.Fatalf("expected the batch to wait for the max latency, got %v", keys)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	fakeClock.Step(time.Hour)
+	select {
+	case keys := <-batches:
+		if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+			t.Errorf("expected batch of a and b, got %v", keys)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected batch sync")
+	}
+
+	// the sync error handler is called with the result of every key, like for the single key sync
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(errsHandled) == 2, nil
+	}); err != nil {
+		t.Fatalf("expected 2 handled results, got %#v", errsHandled)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if errsHandled[0] != nil || errsHandled[1] == nil {
+		t.Errorf("expected nil result of a and error of b, got %#v", errsHandled)
+	}
+}
+
+func TestBaseController_SetWorkers(t *testing.T) {
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	running := make(chan string, 10)
+	release := make(chan struct{})
+	c := New("TestController", func(ctx context.Context, controllerContext framework.Context) error {
+		running <- controllerContext.QueueKey()
+		<-release
+		return nil
+	}, syncContext, 0, nil, nil, nil, time.Minute).(*baseController)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 1)
+
+	for _, key := range []string{"a", "b", "c"} {
+		syncContext.Queue().Add(key)
+	}
+	expectRunning := func(count int) {
+		t.Helper()
+		for i := 0; i < count; i++ {
+			select {
+			case <-running:
+			case <-time.After(10 * time.Second):
+				t.Fatalf("expected %d syncs to run", count)
+			}
+		}
+		select {
+		case key := <-running:
+			t.Fatalf("unexpected sync of %q", key)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	expectRunning(1)
+
+	c.SetWorkers(3)
+	if workers := c.Workers(); workers != 3 {
+		t.Errorf("expected 3 workers, got %d", workers)
+	}
+	expectRunning(2)
+
+	c.SetWorkers(1)
+	if workers := c.Workers(); workers != 1 {
+		t.Errorf("expected 1 worker, got %d", workers)
+	}
+	close(release)
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return syncContext.Queue().Len() == 0, nil
+	}); err != nil {
+		t.Fatal("expected the queue to be drained")
+	}
+}
+
+func TestDesiredWorkers(t *testing.T) {
+	policy := framework.WorkerAutoscalingPolicy{MinWorkers: 1, MaxWorkers: 5, QueueDepthPerWorker: 10, TargetSyncLatency: time.Second}
+	tests := []struct {
+		name        string
+		current     int
+		queueDepth  int
+		syncLatency time.Duration
+		expected    int
+	}{
+		{name: "backlog", current: 1, queueDepth: 35, expected: 4},
+		{name: "backlog over max", current: 1, queueDepth: 100, expected: 5},
+		{name: "slow syncs", current: 2, queueDepth: 5, syncLatency: 2 * time.Second, expected: 3},
+		{name: "steady", current: 2, queueDepth: 5, syncLatency: 100 * time.Millisecond, expected: 2},
+		{name: "idle", current: 3, expected: 2},
+		{name: "idle at min", current: 1, expected: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if desired := desiredWorkers(policy, test.current, test.queueDepth, test.syncLatency); desired != test.expected {
+				t.Errorf("expected %d workers, got %d", test.expected, desired)
+			}
+		})
+	}
+}
+
+func TestBaseController_WorkerAutoscaling(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	release := make(chan struct{})
+	defer close(release)
+	c := New("TestController", func(ctx context.Context, controllerContext framework.Context) error {
+		<-release
+		return nil
+	}, syncContext, 0, nil, nil, nil, time.Minute,
+		WithClock(fakeClock),
+		WithWorkerAutoscaling(framework.WorkerAutoscalingPolicy{MaxWorkers: 4, QueueDepthPerWorker: 2, Interval: time.Minute}),
+	).(*baseController)
+
+	for i := 0; i < 10; i++ {
+		syncContext.Queue().Add(fmt.Sprintf("key-%d", i))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 1)
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatal("expected the autoscaler to wait for the clock")
+	}
+	fakeClock.Step(time.Minute)
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return c.Workers() == 4, nil
+	}); err != nil {
+		t.Fatalf("expected 4 workers, got %d", c.Workers())
+	}
+}
+
+func TestBaseController_WorkerAutoscalingPaused(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	syncContext := context2.New("TestController", eventstesting.NewTestingEventRecorder(t))
+	c := New("TestController", func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}, syncContext, 0, nil, nil, nil, time.Minute,
+		WithClock(fakeClock),
+		WithWorkerAutoscaling(framework.WorkerAutoscalingPolicy{MaxWorkers: 4, QueueDepthPerWorker: 2, Interval: time.Minute}),
+	).(*baseController)
+
+	c.Pause()
+	for i := 0; i < 10; i++ {
+		syncContext.Queue().Add(fmt.Sprintf("key-%d", i))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 1)
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatal("expected the autoscaler to wait for the clock")
+	}
+	// the queue of the paused controller does not scale the workers up
+	for i := 0; i < 3; i++ {
+		fakeClock.Step(time.Minute)
+		time.Sleep(100 * time.Millisecond)
+	}
+	if workers := c.Workers(); workers != 1 {
+		t.Errorf("expected 1 worker while paused, got %d", workers)
+	}
+	if l := syncContext.Queue().Len(); l != 10 {
+		t.Errorf("expected the keys to stay in queue while paused, got %d keys", l)
+	}
+}
+
+func TestBaseController_RemoveEventHandlersOnShutdown(t *testing.T) {
+	tests := []struct {
+		name         string
+		cachesSynced bool
+	}{
+		{name: "shutdown", cachesSynced: true},
+		{name: "shutdown while waiting for caches to sync", cachesSynced: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			informer := &fakeInformer{}
+			registration, _ := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{})
+			// the informer that did not return the registration
+			withoutRegistration := &fakeInformer{}
+			cachesSynced := func() bool { return test.cachesSynced }
+			c := New("test", func(ctx context.Context, controllerContext framework.Context) error {
+				return nil
+			}, context2.New("test", eventstesting.NewTestingEventRecorder(t)), 0, nil, nil, []cache.InformerSynced{cachesSynced}, time.Minute,
+				WithHandlerRegistrations(HandlerRegistration{Informer: informer, Registration: registration}, HandlerRegistration{Informer: withoutRegistration, Registration: nil}))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				c.Run(ctx, 1)
+			}()
+			cancel()
+			<-done
+
+			informer.Lock()
+			defer informer.Unlock()
+			if informer.removedHandlers != 1 {
+				t.Errorf("expected the event handler to be removed on shutdown, removed %d", informer.removedHandlers)
+			}
+			withoutRegistration.Lock()
+			defer withoutRegistration.Unlock()
+			if withoutRegistration.removedHandlers != 0 {
+				t.Errorf("expected the handler without registration to be skipped")
+			}
+		})
+	}
+}
+
+func TestBaseController_ShutDownDebouncersOnShutdown(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	syncContext := context2.New("test", eventstesting.NewTestingEventRecorder(t))
+	debouncer := queue.NewDebouncer(syncContext.Queue(), queue.DebounceConfig{Name: "test", Window: time.Minute, Clock: fakeClock})
+	c := New("test", func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}, syncContext, 0, nil, nil, nil, time.Minute, WithDebouncers(debouncer))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx, 1)
+	}()
+	debouncer.Add("foo")
+	cancel()
+	<-done
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return !fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatal("expected the debouncer timers to be stopped")
+	}
+	if debouncer.Len() != 0 {
+		t.Errorf("expected no pending keys after shutdown, got %d", debouncer.Len())
+	}
+}
+
+func TestHandlerRegistration_HasSynced(t *testing.T) {
+	informer := &fakeInformer{}
+	if !(HandlerRegistration{Informer: informer}).HasSynced() || informer.hasSyncedCount != 1 {
+		t.Errorf("expected the informer HasSynced to be used without registration")
+	}
+	if !(HandlerRegistration{Informer: informer, Registration: fakeRegistration{}}).HasSynced() || informer.hasSyncedCount != 1 {
+		t.Errorf("expected the registration HasSynced to be used")
+	}
+}
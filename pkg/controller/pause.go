@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+var controllerPausedMetric = metrics.NewGaugeVec(&metrics.GaugeOpts{
+	Subsystem:      "controller",
+	Name:           "paused",
+	Help:           "Whether the controller is paused (1) or running (0)",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"name"})
+
+func init() {
+	legacyregistry.MustRegister(controllerPausedMetric)
+}
+
+// pauseState blocks the workers while the controller is paused.
+type pauseState struct {
+	paused bool
+	// resumed is closed when the paused controller is resumed
+	resumed chan struct{}
+	sync.Mutex
+}
+
+var _ framework.Pausable = &baseController{}
+
+// Pause stops the workers from processing the queue. The informers keep adding keys to the queue, the keys are processed
+// when the controller is resumed. The sync that is already running is not interrupted, the workers waiting for the next
+// key hold the key they get until the controller is resumed.
+func (c *baseController) Pause() {
+	c.pause.Lock()
+	defer c.pause.Unlock()
+	if c.pause.paused {
+		return
+	}
+	c.pause.paused = true
+	c.pause.resumed = make(chan struct{})
+	c.reportPausedMetric(true)
+	klog.Infof("Controller %s paused", c.name)
+	if c.syncContext != nil {
+		c.syncContext.Recorder().Eventf("ControllerPaused", "Controller %q paused", c.name)
+	}
+}
+
+// Resume resumes the processing of the queue paused by Pause().
+func (c *baseController) Resume() {
+	c.pause.Lock()
+	defer c.pause.Unlock()
+	if !c.pause.paused {
+		return
+	}
+	c.pause.paused = false
+	close(c.pause.resumed)
+	c.reportPausedMetric(false)
+	klog.Infof("Controller %s resumed", c.name)
+	if c.syncContext != nil {
+		c.syncContext.Recorder().Eventf("ControllerResumed", "Controller %q resumed", c.name)
+	}
+}
+
+// Paused returns true when the controller is paused.
+func (c *baseController) Paused() bool {
+	c.pause.Lock()
+	defer c.pause.Unlock()
+	return c.pause.paused
+}
+
+func (c *baseController) reportPausedMetric(paused bool) {
+	if paused {
+		controllerPausedMetric.WithLabelValues(c.name).Set(1)
+		return
+	}
+	controllerPausedMetric.WithLabelValues(c.name).Set(0)
+}
+
+// holdWhilePaused blocks while the controller is paused. The workers do not take the keys from the queue while the
+// controller is paused, but a worker that already waited for the next key when the controller was paused gets the key.
+// Such key is held by the worker (it is not returned to the queue, so it keeps its priority) and synced when the
+// controller is resumed. It returns false and marks the keys done when the context is cancelled meanwhile.
+func (c *baseController) holdWhilePaused(ctx context.Context, keys ...interface{}) bool {
+	if c.waitWhilePaused(ctx) {
+		return true
+	}
+	for _, key := range keys {
+		c.syncContext.Queue().Done(key)
+	}
+	return false
+}
+
+// waitWhilePaused blocks while the controller is paused. It returns false when the context is cancelled.
+func (c *baseController) waitWhilePaused(ctx context.Context) bool {
+	c.pause.Lock()
+	paused, resumed := c.pause.paused, c.pause.resumed
+	c.pause.Unlock()
+	if !paused {
+		return true
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-resumed:
+		return true
+	}
+}
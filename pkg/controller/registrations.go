@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"fmt"
+
+	errorutil "k8s.io/apimachinery/pkg/util/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+	"github.com/mfojtik/controller-framework/pkg/queue"
+)
+
+// HandlerRegistration is the event handler the controller registered in the informer.
+type HandlerRegistration struct {
+	Informer     framework.Informer
+	Registration cache.ResourceEventHandlerRegistration
+}
+
+// HasSynced returns true when the informer synced and the handler received all objects of the initial list.
+// If the informer did not return the registration, the informer HasSynced is used.
+func (r HandlerRegistration) HasSynced() bool {
+	if r.Registration == nil {
+		return r.Informer.HasSynced()
+	}
+	return r.Registration.HasSynced()
+}
+
+// handlerRemover is implemented by informers that support removing event handlers (eg. SharedInformer).
+type handlerRemover interface {
+	RemoveEventHandler(handle cache.ResourceEventHandlerRegistration) error
+}
+
+// WithHandlerRegistrations sets the event handlers registered by the controller. The handlers are removed from the informers
+// when the controller stops, so the shared informers do not keep adding keys to the queue of stopped controller.
+func WithHandlerRegistrations(registrations ...HandlerRegistration) Option {
+	return func(c *baseController) {
+		c.handlerRegistrations = append(c.handlerRegistrations, registrations...)
+	}
+}
+
+// WithDebouncers sets the debouncers used by the event handlers. The debouncers are shut down when the controller stops,
+// so their timers do not outlive the controller.
+func WithDebouncers(debouncers ...*queue.Debouncer) Option {
+	return func(c *baseController) {
+		c.debouncers = append(c.debouncers, debouncers...)
+	}
+}
+
+// shutDownDebouncers stops the timers of the keys waiting in the debouncers.
+func (c *baseController) shutDownDebouncers() {
+	for _, debouncer := range c.debouncers {
+		debouncer.ShutDown()
+	}
+}
+
+// removeEventHandlers removes the event handlers from the informers that support it.
+// The handlers are removed only once, the following calls do nothing.
+func (c *baseController) removeEventHandlers() {
+	registrations := c.handlerRegistrations
+	c.handlerRegistrations = nil
+	if err := RemoveEventHandlers(registrations...); err != nil {
+		utilruntime.HandleError(fmt.Errorf("%q controller failed to remove event handlers: %v", c.name, err))
+	}
+}
+
+// RemoveEventHandlers removes the event handlers from the informers that support it (eg. SharedInformer). The handlers
+// of the informers that do not support it are left registered. All errors are returned as an aggregate error.
+func RemoveEventHandlers(registrations ...HandlerRegistration) error {
+	var errs []error
+	for _, r := range registrations {
+		informer, ok := r.Informer.(handlerRemover)
+		if !ok || r.Registration == nil {
+			continue
+		}
+		if err := informer.RemoveEventHandler(r.Registration); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errorutil.NewAggregate(errs)
+}
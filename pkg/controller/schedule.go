@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+	"k8s.io/klog/v2"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// maxMissedRuns limits the number of missed runs executed by MissedRunAll policy in a row.
+// Missed runs beyond this limit are skipped.
+const maxMissedRuns = 100
+
+// NamedSchedule is a resync schedule with a name and a function that produce the queue key added when the schedule fires.
+type NamedSchedule struct {
+	Name     string
+	Schedule cron.Schedule
+	// QueueKeyFunc returns the queue key for the scheduled run. If not set, the DefaultQueueKey is used.
+	QueueKeyFunc framework.ScheduleQueueKeyFunc
+	// MissedRunPolicy determines what happens with missed runs. If not set, the missed runs are skipped.
+	MissedRunPolicy framework.MissedRunPolicy
+}
+
+// scheduleState tracks the last and next run of the schedule.
+type scheduleState struct {
+	NamedSchedule
+
+	lastRun time.Time
+	nextRun time.Time
+	sync.Mutex
+}
+
+func (s *scheduleState) status() framework.ScheduleStatus {
+	s.Lock()
+	defer s.Unlock()
+	policy := s.MissedRunPolicy
+	if len(policy) == 0 {
+		policy = framework.MissedRunSkip
+	}
+	return framework.ScheduleStatus{Name: s.Name, MissedRunPolicy: policy, LastRun: s.lastRun, NextRun: s.nextRun}
+}
+
+func (s *scheduleState) setLastRun(lastRun time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.lastRun = lastRun
+}
+
+func (s *scheduleState) setNextRun(nextRun time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.nextRun = nextRun
+}
+
+// pendingScheduledRun is the scheduled run waiting to be processed by a worker.
+type pendingScheduledRun struct {
+	run      framework.ScheduledRun
+	schedule *scheduleState
+	// processed is closed when the sync of the run succeeds or the run is replaced by another run
+	processed chan struct{}
+}
+
+var _ framework.ScheduleInspector = &baseController{}
+
+// Schedules returns the status of all controller resync schedules.
+func (c *baseController) Schedules() []framework.ScheduleStatus {
+	result := make([]framework.ScheduleStatus, 0, len(c.scheduleStates))
+	for _, s := range c.scheduleStates {
+		result = append(result, s.status())
+	}
+	return result
+}
+
+// runSchedule runs the schedule until the context is cancelled.
+// On start, the last run is loaded from the schedule store and missed runs are handled according to the schedule policy.
+func (c *baseController) runSchedule(ctx context.Context, schedule *scheduleState) {
+	defer schedule.setNextRun(time.Time{})
+	clock := c.getClock()
+
+	lastRun := c.loadLastRun(ctx, schedule)
+	from := clock.Now()
+	switch schedule.MissedRunPolicy {
+	case framework.MissedRunOnce:
+		if lastRun.IsZero() {
+			break
+		}
+		// the latest missed run is reported, the sync should not see the time of a run missed long ago
+		if missed := latestRunBefore(schedule.Schedule, lastRun, from); !missed.IsZero() {
+			klog.V(2).Infof("Controller %q missed runs of schedule %q, the latest planned at %s, running once", c.name, schedule.Name, missed)
+			c.fireSchedule(ctx, schedule, framework.ScheduledRun{Name: schedule.Name, PlannedTime: missed, CatchUp: true})
+		}
+	case framework.MissedRunAll:
+		if !lastRun.IsZero() {
+			from = lastRun
+		}
+	}
+
+	missedRuns := 0
+	for {
+		next := schedule.Schedule.Next(from)
+		if next.IsZero() {
+			return
+		}
+		schedule.setNextRun(next)
+
+		now := clock.Now()
+		catchUp := next.Before(now)
+		if catchUp {
+			missedRuns++
+			if missedRuns > maxMissedRuns {
+				klog.Warningf("Controller %q missed more than %d runs of schedule %q, skipping missed runs until %s", c.name, maxMissedRuns, schedule.Name, now)
+				from, missedRuns = now, 0
+				continue
+			}
+		} else {
+			missedRuns = 0
+			timer := clock.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+
+		processed := c.fireSchedule(ctx, schedule, framework.ScheduledRun{Name: schedule.Name, PlannedTime: next, CatchUp: catchUp})
+		if schedule.MissedRunPolicy != framework.MissedRunAll {
+			from = clock.Now()
+			continue
+		}
+		// do not collapse runs, wait till the run is processed by worker before queueing the next one
+		select {
+		case <-ctx.Done():
+			return
+		case <-processed:
+		}
+		from = next
+	}
+}
+
+// loadLastRun returns the last run of the schedule recorded in the schedule store.
+func (c *baseController) loadLastRun(ctx context.Context, schedule *scheduleState) time.Time {
+	if c.scheduleStore == nil {
+		return time.Time{}
+	}
+	lastRun, err := c.scheduleStore.LastRun(ctx, c.name, schedule.Name)
+	if err != nil {
+		klog.Warningf("Controller %q failed to get the last run of schedule %q: %v", c.name, schedule.Name, err)
+		return time.Time{}
+	}
+	schedule.setLastRun(lastRun)
+	return lastRun
+}
+
+// latestRunBefore returns the latest run of the schedule after the after time and before the until time, or zero time
+// when there is no such run. The runs are not walked one by one from the after time, so a long outage of a frequent
+// schedule does not require walking all missed runs.
+func latestRunBefore(schedule cron.Schedule, after, until time.Time) time.Time {
+	first := schedule.Next(after)
+	if first.IsZero() || !first.Before(until) {
+		return time.Time{}
+	}
+	// the constant delay runs are counted from the previous run, the latest one is computed from the first one
+	if constantDelay, ok := schedule.(cron.ConstantDelaySchedule); ok {
+		return first.Add((until.Sub(first) - 1) / constantDelay.Delay * constantDelay.Delay)
+	}
+	// the runs of the other schedules do not depend on the previous run, the search starts close before the until time
+	start := after
+	for probe := time.Second; until.Add(-probe).After(after); probe *= 2 {
+		if run := schedule.Next(until.Add(-probe)); !run.IsZero() && run.Before(until) {
+			start = until.Add(-probe)
+			break
+		}
+	}
+	latest := first
+	for run := schedule.Next(start); !run.IsZero() && run.Before(until); run = schedule.Next(run) {
+		latest = run
+	}
+	return latest
+}
+
+// fireSchedule adds the schedule queue key to the queue. The run is recorded as the last run of the schedule when the sync
+// of the key succeeds (see completePendingScheduledRun).
+// The returned channel is closed when the run is processed by a worker.
+func (c *baseController) fireSchedule(ctx context.Context, schedule *scheduleState, run framework.ScheduledRun) <-chan struct{} {
+	klog.V(4).Infof("Triggering scheduled %q controller run (schedule %q)", c.name, schedule.Name)
+	key := framework.DefaultQueueKey
+	if schedule.QueueKeyFunc != nil {
+		key = schedule.QueueKeyFunc(run)
+	}
+	processed := c.addPendingScheduledRun(key, schedule, run)
+	c.syncContext.Queue().Add(key)
+	return processed
+}
+
+// recordLastRun records the processed run as the last run of the schedule, so the run is not considered missed on restart.
+func (c *baseController) recordLastRun(ctx context.Context, schedule *scheduleState, run framework.ScheduledRun) {
+	schedule.setLastRun(run.PlannedTime)
+	if c.scheduleStore == nil {
+		return
+	}
+	if err := c.scheduleStore.SetLastRun(ctx, c.name, schedule.Name, run.PlannedTime); err != nil {
+		klog.Warningf("Controller %q failed to record the last run of schedule %q: %v", c.name, schedule.Name, err)
+	}
+}
+
+// addPendingScheduledRun remembers the scheduled run for the key, so it can be passed to the Sync() processing the key.
+// When the key is scheduled multiple times before it is processed, the latest run wins.
+func (c *baseController) addPendingScheduledRun(key string, schedule *scheduleState, run framework.ScheduledRun) <-chan struct{} {
+	c.pendingScheduledRunsLock.Lock()
+	defer c.pendingScheduledRunsLock.Unlock()
+	if c.pendingScheduledRuns == nil {
+		c.pendingScheduledRuns = map[string]*pendingScheduledRun{}
+	}
+	if previous, ok := c.pendingScheduledRuns[key]; ok {
+		close(previous.processed)
+	}
+	pending := &pendingScheduledRun{run: run, schedule: schedule, processed: make(chan struct{})}
+	c.pendingScheduledRuns[key] = pending
+	return pending.processed
+}
+
+// getPendingScheduledRun returns the scheduled run waiting for the key. The run stays pending until the sync of the key
+// succeeds (see completePendingScheduledRun), so the retries of the failed sync get the same run.
+func (c *baseController) getPendingScheduledRun(key string) *pendingScheduledRun {
+	c.pendingScheduledRunsLock.Lock()
+	defer c.pendingScheduledRunsLock.Unlock()
+	return c.pendingScheduledRuns[key]
+}
+
+// completePendingScheduledRun records the last run of the schedule and forgets the scheduled run after the successful sync
+// of the key. The run is forgotten only when it is still pending, the run scheduled while the sync was running is kept
+// for the next sync of the key.
+func (c *baseController) completePendingScheduledRun(ctx context.Context, key string, pending *pendingScheduledRun) {
+	c.recordLastRun(ctx, pending.schedule, pending.run)
+
+	c.pendingScheduledRunsLock.Lock()
+	defer c.pendingScheduledRunsLock.Unlock()
+	if c.pendingScheduledRuns[key] != pending {
+		return
+	}
+	delete(c.pendingScheduledRuns, key)
+	close(pending.processed)
+}
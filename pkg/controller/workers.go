@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// defaultAutoscalingInterval is used when the autoscaling policy does not specify the interval.
+const defaultAutoscalingInterval = 30 * time.Second
+
+// syncLatencyWeight is the weight of the latest sync in the moving average of the sync latency.
+const syncLatencyWeight = 0.2
+
+var controllerWorkersMetric = metrics.NewGaugeVec(&metrics.GaugeOpts{
+	Subsystem:      "controller",
+	Name:           "workers",
+	Help:           "Number of controller workers",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"name"})
+
+func init() {
+	legacyregistry.MustRegister(controllerWorkersMetric)
+}
+
+// WithWorkerAutoscaling enables adjusting the number of workers according to the policy.
+// The number of workers passed to Run() is the initial number of workers.
+func WithWorkerAutoscaling(policy framework.WorkerAutoscalingPolicy) Option {
+	return func(c *baseController) {
+		c.autoscalingPolicy = &policy
+	}
+}
+
+// workerPool tracks the running workers so they can be added and removed while the controller is running.
+type workerPool struct {
+	// start starts a new worker that terminates when the stop channel is closed
+	start func(stop <-chan struct{})
+	// stopChs hold the stop channels of running workers
+	stopChs []chan struct{}
+	// desired is the number of workers set before the controller was started
+	desired int
+
+	// syncLatency is the moving average of the sync duration
+	syncLatency time.Duration
+
+	sync.Mutex
+}
+
+var _ framework.WorkerScaler = &baseController{}
+
+// SetWorkers changes the number of workers. When scaling down, the workers terminate after finishing their current item.
+// Idle workers waiting for the queue terminate after they process the next item.
+// When called before Run(), the number of workers passed to Run() is used.
+func (c *baseController) SetWorkers(workers int) {
+	if workers < 0 {
+		workers = 0
+	}
+	c.workers.Lock()
+	defer c.workers.Unlock()
+	c.setWorkersLocked(workers)
+}
+
+func (c *baseController) setWorkersLocked(workers int) {
+	c.workers.desired = workers
+	if c.workers.start == nil {
+		return
+	}
+	if current := len(c.workers.stopChs); current != workers {
+		klog.V(2).Infof("Scaling %s controller workers from %d to %d", c.name, current, workers)
+	}
+	for len(c.workers.stopChs) < workers {
+		stopCh := make(chan struct{})
+		c.workers.stopChs = append(c.workers.stopChs, stopCh)
+		c.workers.start(stopCh)
+	}
+	for len(c.workers.stopChs) > workers {
+		last := len(c.workers.stopChs) - 1
+		close(c.workers.stopChs[last])
+		c.workers.stopChs = c.workers.stopChs[:last]
+	}
+	controllerWorkersMetric.WithLabelValues(c.name).Set(float64(workers))
+}
+
+// Workers returns the current number of workers.
+func (c *baseController) Workers() int {
+	c.workers.Lock()
+	defer c.workers.Unlock()
+	if c.workers.start == nil {
+		return c.workers.desired
+	}
+	return len(c.workers.stopChs)
+}
+
+// startWorkers starts the given number of workers using the start function.
+func (c *baseController) startWorkers(workers int, start func(stop <-chan struct{})) {
+	c.workers.Lock()
+	defer c.workers.Unlock()
+	c.workers.start = start
+	c.setWorkersLocked(workers)
+}
+
+// stopWorkers stops all workers and prevents new workers from being started.
+func (c *baseController) stopWorkers() {
+	c.workers.Lock()
+	defer c.workers.Unlock()
+	c.setWorkersLocked(0)
+	c.workers.start = nil
+}
+
+// observeSyncLatency updates the moving average of the sync duration.
+func (c *baseController) observeSyncLatency(latency time.Duration) {
+	c.workers.Lock()
+	defer c.workers.Unlock()
+	if c.workers.syncLatency == 0 {
+		c.workers.syncLatency = latency
+		return
+	}
+	c.workers.syncLatency = time.Duration(syncLatencyWeight*float64(latency) + (1-syncLatencyWeight)*float64(c.workers.syncLatency))
+}
+
+// desiredWorkers returns the number of workers the policy requires for the current queue depth and sync latency.
+func desiredWorkers(policy framework.WorkerAutoscalingPolicy, current, queueDepth int, syncLatency time.Duration) int {
+	desired := current
+	switch {
+	case policy.QueueDepthPerWorker > 0 && queueDepth > current*policy.QueueDepthPerWorker:
+		desired = (queueDepth + policy.QueueDepthPerWorker - 1) / policy.QueueDepthPerWorker
+	case policy.TargetSyncLatency > 0 && syncLatency > policy.TargetSyncLatency && queueDepth > 0:
+		desired = current + 1
+	case queueDepth == 0:
+		desired = current - 1
+	}
+	if policy.MaxWorkers > 0 && desired > policy.MaxWorkers {
+		desired = policy.MaxWorkers
+	}
+	if desired < policy.MinWorkers {
+		desired = policy.MinWorkers
+	}
+	if desired < 1 {
+		desired = 1
+	}
+	return desired
+}
+
+// runWorkerAutoscaler periodically adjusts the number of workers according to the autoscaling policy.
+func (c *baseController) runWorkerAutoscaler(ctx context.Context, policy framework.WorkerAutoscalingPolicy) {
+	interval := policy.Interval
+	if interval <= 0 {
+		interval = defaultAutoscalingInterval
+	}
+	ticker := c.getClock().NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+		// the queue of the paused controller grows on purpose, the workers would not process it anyway
+		if c.Paused() {
+			continue
+		}
+		c.workers.Lock()
+		syncLatency := c.workers.syncLatency
+		current := len(c.workers.stopChs)
+		c.workers.Unlock()
+
+		if desired := desiredWorkers(policy, current, c.syncContext.Queue().Len(), syncLatency); desired != current {
+			c.SetWorkers(desired)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// DefaultPodNameFile is the default path to the downward API file containing the current pod name.
+	DefaultPodNameFile = "/etc/podinfo/name"
+	// DefaultPodNamespaceFile is the default path to the downward API file containing the current pod namespace.
+	DefaultPodNamespaceFile = "/etc/podinfo/namespace"
+
+	// podNamespaceEnv is a name of environment variable inside container that specifies the namespace of the current pod.
+	podNamespaceEnv = "POD_NAMESPACE"
+
+	// serviceAccountNamespaceFile is mounted to every pod that use service account token.
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+	// maxOwnerChainDepth protects against owner reference cycles.
+	maxOwnerChainDepth = 10
+)
+
+// ControllerReferenceResolver resolves an object reference to the top-level controller managing the pod where this process runs.
+// Unlike GetControllerReferenceForCurrentPod, the resolver follows arbitrary controller owner references (StatefulSet,
+// DaemonSet, Job to CronJob, custom operators, etc.) using the dynamic client.
+// The pod name and namespace are read from the downward API files, the POD_NAME and POD_NAMESPACE environment variables
+// or the service account namespace file (in that order).
+// The successfully resolved reference is cached, so it is safe to call Resolve() every time a recorder is created.
+type ControllerReferenceResolver struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+
+	podNameFile      string
+	podNamespaceFile string
+	readFile         func(name string) ([]byte, error)
+	getEnv           func(key string) string
+
+	cached *corev1.ObjectReference
+	sync.Mutex
+}
+
+// NewControllerReferenceResolver returns a resolver that use the given dynamic client to walk the owner references.
+func NewControllerReferenceResolver(client dynamic.Interface) *ControllerReferenceResolver {
+	return &ControllerReferenceResolver{
+		client:           client,
+		podNameFile:      DefaultPodNameFile,
+		podNamespaceFile: DefaultPodNamespaceFile,
+		readFile:         os.ReadFile,
+		getEnv:           os.Getenv,
+	}
+}
+
+// WithRESTMapper allows to specify REST mapper used to map the owner kinds to resources.
+// If not specified, the resources are guessed from the kinds and the owners are assumed to be namespaced first.
+func (r *ControllerReferenceResolver) WithRESTMapper(mapper meta.RESTMapper) *ControllerReferenceResolver {
+	r.mapper = mapper
+	return r
+}
+
+// WithPodInfoFiles allows to override the paths to the downward API files containing the pod name and namespace.
+func (r *ControllerReferenceResolver) WithPodInfoFiles(podNameFile, podNamespaceFile string) *ControllerReferenceResolver {
+	r.podNameFile = podNameFile
+	r.podNamespaceFile = podNamespaceFile
+	return r
+}
+
+// Resolve returns an object reference to the top-level controller of the current pod.
+// The targetNamespace is used when the pod namespace cannot be determined.
+// Even if this method returns an error, it always return valid reference (the last resolved owner or the namespace).
+// It allows the callers to control the logging and decide to fail or accept the reference.
+func (r *ControllerReferenceResolver) Resolve(ctx context.Context, targetNamespace string) (*corev1.ObjectReference, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.cached != nil {
+		return r.cached.DeepCopy(), nil
+	}
+
+	podName, podNamespace := r.podNameAndNamespace()
+	if len(podNamespace) == 0 {
+		podNamespace = targetNamespace
+	}
+	if len(podName) == 0 {
+		return getControllerReferenceForNamespace(podNamespace), fmt.Errorf("unable to determine the current pod name from %q or %q env variable", r.podNameFile, podNameEnv)
+	}
+
+	reference, err := r.resolveOwnerChain(ctx, &corev1.ObjectReference{Kind: "Pod", APIVersion: "v1", Name: podName, Namespace: podNamespace})
+	if err != nil {
+		return reference, err
+	}
+	r.cached = reference
+	return reference.DeepCopy(), nil
+}
+
+func (r *ControllerReferenceResolver) podNameAndNamespace() (string, string) {
+	name := r.readTrimmedFile(r.podNameFile)
+	if len(name) == 0 {
+		name = r.getEnv(podNameEnv)
+	}
+	namespace := r.readTrimmedFile(r.podNamespaceFile)
+	if len(namespace) == 0 {
+		namespace = r.getEnv(podNamespaceEnv)
+	}
+	if len(namespace) == 0 {
+		namespace = r.readTrimmedFile(serviceAccountNamespaceFile)
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(namespace)
+}
+
+func (r *ControllerReferenceResolver) readTrimmedFile(name string) string {
+	if len(name) == 0 {
+		return ""
+	}
+	data, err := r.readFile(name)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// resolveOwnerChain follows the controller owner references starting from the given pod.
+func (r *ControllerReferenceResolver) resolveOwnerChain(ctx context.Context, pod *corev1.ObjectReference) (*corev1.ObjectReference, error) {
+	current := pod
+	visited := map[string]bool{}
+	for depth := 0; depth < maxOwnerChainDepth; depth++ {
+		obj, err := r.get(ctx, current)
+		if err != nil {
+			if current == pod {
+				return getControllerReferenceForNamespace(pod.Namespace), err
+			}
+			// We know the owner identity from the owner reference, but we can't walk further.
+			return current, fmt.Errorf("unable to get %s %s/%s: %w", current.Kind, current.Namespace, current.Name, err)
+		}
+		current.UID = obj.GetUID()
+		current.Namespace = obj.GetNamespace()
+
+		visited[referenceKey(current)] = true
+		owner := metav1.GetControllerOfNoCopy(obj)
+		if owner == nil {
+			return current, nil
+		}
+		if visited[referenceKey(makeObjectReference(owner, current.Namespace))] {
+			return current, fmt.Errorf("owner reference cycle detected at %s %s/%s", owner.Kind, current.Namespace, owner.Name)
+		}
+		current = makeObjectReference(owner, current.Namespace)
+	}
+	return current, fmt.Errorf("owner reference chain is longer than %d", maxOwnerChainDepth)
+}
+
+func referenceKey(reference *corev1.ObjectReference) string {
+	return strings.Join([]string{reference.APIVersion, reference.Kind, reference.Namespace, reference.Name}, "/")
+}
+
+// get fetches the referenced object. If the object scope is not known, the namespaced object is tried first.
+func (r *ControllerReferenceResolver) get(ctx context.Context, reference *corev1.ObjectReference) (*unstructured.Unstructured, error) {
+	gvk := schema.FromAPIVersionAndKind(reference.APIVersion, reference.Kind)
+	if r.mapper != nil {
+		mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, err
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+			return r.client.Resource(mapping.Resource).Get(ctx, reference.Name, metav1.GetOptions{})
+		}
+		return r.client.Resource(mapping.Resource).Namespace(reference.Namespace).Get(ctx, reference.Name, metav1.GetOptions{})
+	}
+
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	obj, err := r.client.Resource(gvr).Namespace(reference.Namespace).Get(ctx, reference.Name, metav1.GetOptions{})
+	if err == nil || len(reference.Namespace) == 0 {
+		return obj, err
+	}
+	// the owner might be cluster scoped
+	if clusterObj, clusterErr := r.client.Resource(gvr).Get(ctx, reference.Name, metav1.GetOptions{}); clusterErr == nil {
+		return clusterObj, nil
+	}
+	return nil, err
+}
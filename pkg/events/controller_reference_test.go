@@ -0,0 +1,178 @@
+package events
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func controllerOwner(apiVersion, kind, name string) []metav1.OwnerReference {
+	truePtr := true
+	return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, UID: types.UID("uid-" + name), Controller: &truePtr}}
+}
+
+func ownedObjectMeta(namespace, name string, owners []metav1.OwnerReference) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID("uid-" + name), OwnerReferences: owners}
+}
+
+func TestControllerReferenceResolver(t *testing.T) {
+	operator := &unstructured.Unstructured{}
+	operator.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Operator"})
+	operator.SetName("my-operator")
+	operator.SetUID("uid-my-operator")
+
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		files           map[string]string
+		env             map[string]string
+		expectKind      string
+		expectName      string
+		expectNamespace string
+		expectErr       bool
+	}{
+		{
+			name: "deployment",
+			objects: []runtime.Object{
+				&corev1.Pod{ObjectMeta: ownedObjectMeta("ns", "pod", controllerOwner("apps/v1", "ReplicaSet", "rs"))},
+				&appsv1.ReplicaSet{ObjectMeta: ownedObjectMeta("ns", "rs", controllerOwner("apps/v1", "Deployment", "deployment"))},
+				&appsv1.Deployment{ObjectMeta: ownedObjectMeta("ns", "deployment", nil)},
+			},
+			env:             map[string]string{"POD_NAME": "pod", "POD_NAMESPACE": "ns"},
+			expectKind:      "Deployment",
+			expectName:      "deployment",
+			expectNamespace: "ns",
+		},
+		{
+			name: "statefulset from downward API files",
+			objects: []runtime.Object{
+				&corev1.Pod{ObjectMeta: ownedObjectMeta("ns", "pod-0", controllerOwner("apps/v1", "StatefulSet", "sts"))},
+				&appsv1.StatefulSet{ObjectMeta: ownedObjectMeta("ns", "sts", nil)},
+			},
+			files:           map[string]string{DefaultPodNameFile: "pod-0\n", DefaultPodNamespaceFile: "ns\n"},
+			env:             map[string]string{"POD_NAME": "wrong"},
+			expectKind:      "StatefulSet",
+			expectName:      "sts",
+			expectNamespace: "ns",
+		},
+		{
+			name: "daemonset",
+			objects: []runtime.Object{
+				&corev1.Pod{ObjectMeta: ownedObjectMeta("ns", "pod", controllerOwner("apps/v1", "DaemonSet", "ds"))},
+				&appsv1.DaemonSet{ObjectMeta: ownedObjectMeta("ns", "ds", nil)},
+			},
+			env:             map[string]string{"POD_NAME": "pod"},
+			expectKind:      "DaemonSet",
+			expectName:      "ds",
+			expectNamespace: "ns",
+		},
+		{
+			name: "cronjob",
+			objects: []runtime.Object{
+				&corev1.Pod{ObjectMeta: ownedObjectMeta("ns", "pod", controllerOwner("batch/v1", "Job", "job"))},
+				&batchv1.Job{ObjectMeta: ownedObjectMeta("ns", "job", controllerOwner("batch/v1", "CronJob", "cron"))},
+				&batchv1.CronJob{ObjectMeta: ownedObjectMeta("ns", "cron", nil)},
+			},
+			env:             map[string]string{"POD_NAME": "pod"},
+			expectKind:      "CronJob",
+			expectName:      "cron",
+			expectNamespace: "ns",
+		},
+		{
+			name: "cluster scoped custom operator",
+			objects: []runtime.Object{
+				&corev1.Pod{ObjectMeta: ownedObjectMeta("ns", "pod", controllerOwner("apps/v1", "Deployment", "deployment"))},
+				&appsv1.Deployment{ObjectMeta: ownedObjectMeta("ns", "deployment", controllerOwner("example.com/v1", "Operator", "my-operator"))},
+				operator,
+			},
+			env:             map[string]string{"POD_NAME": "pod"},
+			expectKind:      "Operator",
+			expectName:      "my-operator",
+			expectNamespace: "",
+		},
+		{
+			name: "missing owner returns the last known owner",
+			objects: []runtime.Object{
+				&corev1.Pod{ObjectMeta: ownedObjectMeta("ns", "pod", controllerOwner("apps/v1", "ReplicaSet", "rs"))},
+			},
+			env:             map[string]string{"POD_NAME": "pod"},
+			expectKind:      "ReplicaSet",
+			expectName:      "rs",
+			expectNamespace: "ns",
+			expectErr:       true,
+		},
+		{
+			name:            "missing pod name falls back to namespace",
+			expectKind:      "Namespace",
+			expectName:      "ns",
+			expectNamespace: "ns",
+			expectErr:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClient(scheme.Scheme, test.objects...)
+			resolver := NewControllerReferenceResolver(client)
+			resolver.readFile = func(name string) ([]byte, error) {
+				if content, ok := test.files[name]; ok {
+					return []byte(content), nil
+				}
+				return nil, os.ErrNotExist
+			}
+			resolver.getEnv = func(key string) string {
+				return test.env[key]
+			}
+
+			reference, err := resolver.Resolve(context.TODO(), "ns")
+			if err != nil && !test.expectErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && test.expectErr {
+				t.Fatalf("expected error, got none")
+			}
+			if reference.Kind != test.expectKind || reference.Name != test.expectName || reference.Namespace != test.expectNamespace {
+				t.Errorf("expected %s %s/%s, got %s %s/%s", test.expectKind, test.expectNamespace, test.expectName, reference.Kind, reference.Namespace, reference.Name)
+			}
+		})
+	}
+}
+
+func TestControllerReferenceResolverCache(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(scheme.Scheme,
+		&corev1.Pod{ObjectMeta: ownedObjectMeta("ns", "pod", controllerOwner("apps/v1", "StatefulSet", "sts"))},
+		&appsv1.StatefulSet{ObjectMeta: ownedObjectMeta("ns", "sts", nil)},
+	)
+	resolver := NewControllerReferenceResolver(client)
+	resolver.readFile = func(string) ([]byte, error) { return nil, os.ErrNotExist }
+	resolver.getEnv = func(key string) string {
+		if key == "POD_NAME" {
+			return "pod"
+		}
+		return ""
+	}
+
+	for i := 0; i < 3; i++ {
+		reference, err := resolver.Resolve(context.TODO(), "ns")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reference.Kind != "StatefulSet" || reference.UID != "uid-sts" {
+			t.Errorf("unexpected reference: %#v", reference)
+		}
+	}
+	if actions := len(client.Actions()); actions != 2 {
+		t.Errorf("expected the reference to be cached after 2 get calls, got %d actions", actions)
+	}
+}
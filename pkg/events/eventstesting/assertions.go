@@ -0,0 +1,184 @@
+package eventstesting
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/mfojtik/controller-framework/pkg/events"
+)
+
+// TestingT is the subset of testing.TB used by the event assertions.
+// Both *testing.T and *testing.B satisfy this interface.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// EventMatcher describes an event expected to be recorded.
+// Empty Reason or Type match any reason or type, nil Message matches any message.
+type EventMatcher struct {
+	Reason  string
+	Type    string
+	Message *regexp.Regexp
+}
+
+// MatchEvent returns an event matcher for given reason, event type and message regular expression.
+// Empty strings match anything. This function panics when the messageRegexp does not compile.
+func MatchEvent(reason, eventType, messageRegexp string) EventMatcher {
+	m := EventMatcher{Reason: reason, Type: eventType}
+	if len(messageRegexp) > 0 {
+		m.Message = regexp.MustCompile(messageRegexp)
+	}
+	return m
+}
+
+// Matches returns true if the event satisfy the matcher.
+func (m EventMatcher) Matches(event *corev1.Event) bool {
+	if event == nil {
+		return false
+	}
+	if len(m.Reason) > 0 && m.Reason != event.Reason {
+		return false
+	}
+	if len(m.Type) > 0 && m.Type != event.Type {
+		return false
+	}
+	if m.Message != nil && !m.Message.MatchString(event.Message) {
+		return false
+	}
+	return true
+}
+
+func (m EventMatcher) String() string {
+	var parts []string
+	parts = append(parts, fmt.Sprintf("type=%s", valueOrAny(m.Type)))
+	parts = append(parts, fmt.Sprintf("reason=%s", valueOrAny(m.Reason)))
+	if m.Message != nil {
+		parts = append(parts, fmt.Sprintf("message=~%q", m.Message.String()))
+	} else {
+		parts = append(parts, "message=*")
+	}
+	return strings.Join(parts, " ")
+}
+
+// ExpectEvent fails the test when the recorder does not contain an event with given reason, type and message regular expression.
+// The first matching event is returned, or nil if there is no matching event.
+func ExpectEvent(t TestingT, recorder events.InMemoryRecorder, reason, eventType, messageRegexp string) *corev1.Event {
+	t.Helper()
+	matcher := MatchEvent(reason, eventType, messageRegexp)
+	recorded := recorder.Events()
+	for _, event := range recorded {
+		if matcher.Matches(event) {
+			return event
+		}
+	}
+	t.Errorf("expected event matching %s was not recorded\n%s", matcher, formatEvents(recorded))
+	return nil
+}
+
+// ExpectNoEvent fails the test when the recorder contain an event with given reason, type and message regular expression.
+func ExpectNoEvent(t TestingT, recorder events.InMemoryRecorder, reason, eventType, messageRegexp string) {
+	t.Helper()
+	matcher := MatchEvent(reason, eventType, messageRegexp)
+	recorded := recorder.Events()
+	for _, event := range recorded {
+		if matcher.Matches(event) {
+			t.Errorf("unexpected event matching %s was recorded: %s\n%s", matcher, formatEvent(event), formatEvents(recorded))
+			return
+		}
+	}
+}
+
+// ExpectNoWarnings fails the test when the recorder contain any Warning event.
+func ExpectNoWarnings(t TestingT, recorder events.InMemoryRecorder) {
+	t.Helper()
+	recorded := recorder.Events()
+	var warnings []*corev1.Event
+	for _, event := range recorded {
+		if event.Type == corev1.EventTypeWarning {
+			warnings = append(warnings, event)
+		}
+	}
+	if len(warnings) > 0 {
+		t.Errorf("expected no warning events, got %d\n%s", len(warnings), formatEvents(warnings))
+	}
+}
+
+// ExpectEventsInOrder fails the test when the recorded events does not contain events matching all matchers in the given order.
+// Other events can be recorded between the matching events.
+func ExpectEventsInOrder(t TestingT, recorder events.InMemoryRecorder, matchers ...EventMatcher) {
+	t.Helper()
+	recorded := recorder.Events()
+	next := 0
+	for _, event := range recorded {
+		if next == len(matchers) {
+			break
+		}
+		if matchers[next].Matches(event) {
+			next++
+		}
+	}
+	if next == len(matchers) {
+		return
+	}
+	var expected []string
+	for i, m := range matchers {
+		marker := " "
+		if i == next {
+			marker = ">"
+		}
+		expected = append(expected, fmt.Sprintf("%s %2d. %s", marker, i+1, m))
+	}
+	t.Errorf("expected events in order were not recorded, first missing is #%d\nexpected:\n%s\n%s", next+1, strings.Join(expected, "\n"), formatEvents(recorded))
+}
+
+// WaitForEvent polls the recorder until an event with given reason, type and message regular expression is recorded.
+// This is useful for controllers that record events asynchronously.
+// The test fails if no matching event is recorded before timeout, in that case nil is returned.
+func WaitForEvent(t TestingT, recorder events.InMemoryRecorder, timeout time.Duration, reason, eventType, messageRegexp string) *corev1.Event {
+	t.Helper()
+	matcher := MatchEvent(reason, eventType, messageRegexp)
+	var found *corev1.Event
+	err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, timeout, true, func(ctx context.Context) (bool, error) {
+		for _, event := range recorder.Events() {
+			if matcher.Matches(event) {
+				found = event
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		t.Errorf("expected event matching %s was not recorded within %s\n%s", matcher, timeout, formatEvents(recorder.Events()))
+		return nil
+	}
+	return found
+}
+
+func formatEvent(event *corev1.Event) string {
+	return fmt.Sprintf("type=%s reason=%s message=%q component=%s", event.Type, event.Reason, event.Message, event.Source.Component)
+}
+
+func formatEvents(recorded []*corev1.Event) string {
+	if len(recorded) == 0 {
+		return "recorded events: <none>"
+	}
+	lines := []string{fmt.Sprintf("recorded events (%d):", len(recorded))}
+	for i, event := range recorded {
+		lines = append(lines, fmt.Sprintf("  %2d. %s", i+1, formatEvent(event)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func valueOrAny(s string) string {
+	if len(s) == 0 {
+		return "*"
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+package eventstesting
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/mfojtik/controller-framework/pkg/events"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpectEvent(t *testing.T) {
+	recorder := events.NewInMemoryRecorder("test")
+	recorder.Eventf("SecretCreated", "secret %q created", "foo")
+	recorder.Warning("SecretFailed", "failed to create secret")
+
+	ft := &fakeT{}
+	if e := ExpectEvent(ft, recorder, "SecretCreated", corev1.EventTypeNormal, `secret "foo"`); e == nil {
+		t.Errorf("expected event to be returned")
+	}
+	ExpectEvent(ft, recorder, "SecretFailed", "", "")
+	if len(ft.errors) != 0 {
+		t.Fatalf("unexpected errors: %v", ft.errors)
+	}
+
+	ExpectEvent(ft, recorder, "SecretCreated", corev1.EventTypeWarning, "")
+	if len(ft.errors) != 1 {
+		t.Fatalf("expected one error, got %v", ft.errors)
+	}
+	if !strings.Contains(ft.errors[0], "reason=SecretFailed") || !strings.Contains(ft.errors[0], "recorded events (2)") {
+		t.Errorf("expected error to list recorded events, got:\n%s", ft.errors[0])
+	}
+}
+
+func TestExpectNoWarnings(t *testing.T) {
+	recorder := events.NewInMemoryRecorder("test")
+	recorder.Event("Normal", "all good")
+
+	ft := &fakeT{}
+	ExpectNoWarnings(ft, recorder)
+	if len(ft.errors) != 0 {
+		t.Fatalf("unexpected errors: %v", ft.errors)
+	}
+
+	recorder.Warning("Bad", "not good")
+	ExpectNoWarnings(ft, recorder)
+	if len(ft.errors) != 1 {
+		t.Fatalf("expected one error, got %v", ft.errors)
+	}
+}
+
+func TestExpectEventsInOrder(t *testing.T) {
+	recorder := events.NewInMemoryRecorder("test")
+	recorder.Event("First", "1")
+	recorder.Event("Unrelated", "x")
+	recorder.Event("Second", "2")
+	recorder.Warning("Third", "3")
+
+	ft := &fakeT{}
+	ExpectEventsInOrder(ft, recorder,
+		MatchEvent("First", "", ""),
+		MatchEvent("Second", corev1.EventTypeNormal, ""),
+		MatchEvent("Third", corev1.EventTypeWarning, "^3$"),
+	)
+	if len(ft.errors) != 0 {
+		t.Fatalf("unexpected errors: %v", ft.errors)
+	}
+
+	ExpectEventsInOrder(ft, recorder, MatchEvent("Second", "", ""), MatchEvent("First", "", ""))
+	if len(ft.errors) != 1 {
+		t.Fatalf("expected one error, got %v", ft.errors)
+	}
+	if !strings.Contains(ft.errors[0], "first missing is #2") {
+		t.Errorf("expected error to point to the missing event, got:\n%s", ft.errors[0])
+	}
+}
+
+func TestWaitForEvent(t *testing.T) {
+	recorder := events.NewInMemoryRecorder("test")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		recorder.Event("Async", "done")
+	}()
+
+	ft := &fakeT{}
+	if e := WaitForEvent(ft, recorder, 5*time.Second, "Async", "", "done"); e == nil {
+		t.Fatalf("expected event, got errors: %v", ft.errors)
+	}
+
+	if e := WaitForEvent(ft, recorder, 50*time.Millisecond, "Never", "", ""); e != nil {
+		t.Errorf("expected no event, got %v", e)
+	}
+	if len(ft.errors) != 1 {
+		t.Fatalf("expected one error, got %v", ft.errors)
+	}
+}
@@ -17,6 +17,14 @@ func (r *TestingEventRecorder) WithContext(ctx context.Context) events.Recorder
 	return r
 }
 
+func (r *TestingEventRecorder) WithLabels(labels map[string]string) events.Recorder {
+	return r
+}
+
+func (r *TestingEventRecorder) WithAnnotations(annotations map[string]string) events.Recorder {
+	return r
+}
+
 // NewTestingEventRecorder provides event recorder that will log all recorded events to the error log.
 func NewTestingEventRecorder(t *testing.T) events.Recorder {
 	return &TestingEventRecorder{t: t, component: "test"}
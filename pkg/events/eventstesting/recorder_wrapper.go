@@ -12,7 +12,24 @@ type EventRecorder struct {
 }
 
 func (e *EventRecorder) WithContext(ctx context.Context) events.Recorder {
-	return e
+	return &EventRecorder{
+		realEventRecorder:    e.realEventRecorder.WithContext(ctx),
+		testingEventRecorder: e.testingEventRecorder,
+	}
+}
+
+func (e *EventRecorder) WithLabels(labels map[string]string) events.Recorder {
+	return &EventRecorder{
+		realEventRecorder:    e.realEventRecorder.WithLabels(labels),
+		testingEventRecorder: e.testingEventRecorder,
+	}
+}
+
+func (e *EventRecorder) WithAnnotations(annotations map[string]string) events.Recorder {
+	return &EventRecorder{
+		realEventRecorder:    e.realEventRecorder.WithAnnotations(annotations),
+		testingEventRecorder: e.testingEventRecorder,
+	}
 }
 
 func NewEventRecorder(t *testing.T, r events.Recorder) events.Recorder {
@@ -45,13 +62,19 @@ func (e *EventRecorder) Warningf(reason, messageFmt string, args ...interface{})
 }
 
 func (e *EventRecorder) ForComponent(componentName string) events.Recorder {
-	return e
+	return &EventRecorder{
+		realEventRecorder:    e.realEventRecorder.ForComponent(componentName),
+		testingEventRecorder: e.testingEventRecorder.ForComponent(componentName).(*TestingEventRecorder),
+	}
 }
 
 func (e *EventRecorder) WithComponentSuffix(componentNameSuffix string) events.Recorder {
-	return e
+	return &EventRecorder{
+		realEventRecorder:    e.realEventRecorder.WithComponentSuffix(componentNameSuffix),
+		testingEventRecorder: e.testingEventRecorder.WithComponentSuffix(componentNameSuffix).(*TestingEventRecorder),
+	}
 }
 
 func (e *EventRecorder) ComponentName() string {
-	return "test-recorder"
+	return e.realEventRecorder.ComponentName()
 }
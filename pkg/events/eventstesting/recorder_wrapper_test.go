@@ -0,0 +1,26 @@
+package eventstesting
+
+import (
+	"testing"
+
+	"github.com/mfojtik/controller-framework/pkg/events"
+)
+
+func TestEventRecorder_ForComponent(t *testing.T) {
+	inMemory := events.NewInMemoryRecorder("test")
+	recorder := NewEventRecorder(t, inMemory)
+
+	suffixed := recorder.ForComponent("operator").WithComponentSuffix("controller")
+	if name := suffixed.ComponentName(); name != "operator-controller" {
+		t.Errorf("expected operator-controller component, got %q", name)
+	}
+	if name := recorder.ComponentName(); name != "test" {
+		t.Errorf("expected the original recorder to keep test component, got %q", name)
+	}
+
+	suffixed.Event("Reason", "message")
+	recorded := inMemory.Events()
+	if len(recorded) != 1 || recorded[0].Source.Component != "operator-controller" {
+		t.Errorf("expected event from operator-controller component, got %#v", recorded)
+	}
+}
@@ -2,7 +2,6 @@ package events
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -16,6 +15,8 @@ import (
 )
 
 // Recorder is a simple event recording interface.
+// All derivations (ForComponent, WithComponentSuffix, WithContext, WithLabels and WithAnnotations) return a new recorder
+// and never modify the receiver, so a single recorder can be safely shared across goroutines.
 type Recorder interface {
 	Event(reason, message string)
 	Eventf(reason, messageFmt string, args ...interface{})
@@ -33,6 +34,14 @@ type Recorder interface {
 	// WithContext allows to set a context for event create API calls.
 	WithContext(ctx context.Context) Recorder
 
+	// WithLabels returns a recorder that stamps the given labels on every emitted event.
+	// The labels are merged with the labels set by previous derivations, the new values win.
+	WithLabels(labels map[string]string) Recorder
+
+	// WithAnnotations returns a recorder that stamps the given annotations on every emitted event.
+	// The annotations are merged with the annotations set by previous derivations, the new values win.
+	WithAnnotations(annotations map[string]string) Recorder
+
 	// ComponentName returns the current source component name for the event.
 	// This allows to suffix the original component name with 'sub-component'.
 	ComponentName() string
@@ -40,6 +49,44 @@ type Recorder interface {
 	Shutdown()
 }
 
+// eventMetadata holds the labels and annotations stamped on emitted events.
+type eventMetadata struct {
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// withLabels returns a copy of metadata with the given labels merged in.
+func (m eventMetadata) withLabels(labels map[string]string) eventMetadata {
+	return eventMetadata{labels: mergeStringMaps(m.labels, labels), annotations: m.annotations}
+}
+
+// withAnnotations returns a copy of metadata with the given annotations merged in.
+func (m eventMetadata) withAnnotations(annotations map[string]string) eventMetadata {
+	return eventMetadata{labels: m.labels, annotations: mergeStringMaps(m.annotations, annotations)}
+}
+
+// apply stamps the labels and annotations on given event.
+func (m eventMetadata) apply(event *corev1.Event) *corev1.Event {
+	if len(m.labels) > 0 {
+		event.Labels = mergeStringMaps(event.Labels, m.labels)
+	}
+	if len(m.annotations) > 0 {
+		event.Annotations = mergeStringMaps(event.Annotations, m.annotations)
+	}
+	return event
+}
+
+// mergeStringMaps returns a new map with values from all maps, later maps override the earlier ones.
+func mergeStringMaps(maps ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			result[k] = v
+		}
+	}
+	return result
+}
+
 // podNameEnv is a name of environment variable inside container that specifies the name of the current replica set.
 // This replica set name is then used as a source/involved object for operator events.
 const podNameEnv = "POD_NAME"
@@ -50,23 +97,18 @@ var podNameEnvFunc = func() string {
 }
 
 // GetControllerReferenceForCurrentPod provides an object reference to a controller managing the pod/container where this process runs.
-// The pod name must be provided via the POD_NAME name.
+// The pod name must be provided via the POD_NAME name, otherwise the reference to the namespace is returned with an error.
 // Even if this method returns an error, it always return valid reference to the namespace. It allows the callers to control the logging
 // and decide to fail or accept the namespace.
+// This only follows the Pod -> ReplicaSet -> owner chain.
+//
+// Deprecated: Use ControllerReferenceResolver, which follows arbitrary owners and reads the pod name from the downward API files.
 func GetControllerReferenceForCurrentPod(ctx context.Context, client kubernetes.Interface, targetNamespace string, reference *corev1.ObjectReference) (*corev1.ObjectReference, error) {
 	if reference == nil {
-		// Try to get the pod name via POD_NAME environment variable
 		reference := &corev1.ObjectReference{Kind: "Pod", Name: podNameEnvFunc(), Namespace: targetNamespace}
-		if len(reference.Name) != 0 {
-			return GetControllerReferenceForCurrentPod(ctx, client, targetNamespace, reference)
-		}
-		// If that fails, lets try to guess the pod by listing all pods in namespaces and using the first pod in the list
-		reference, err := guessControllerReferenceForNamespace(ctx, client.CoreV1().Pods(targetNamespace))
-		if err != nil {
-			// If this fails, do not give up with error but instead use the namespace as controller reference for the pod
-			// NOTE: This is last resort, if we see this often it might indicate something is wrong in the cluster.
-			//       In some cases this might help with flakes.
-			return getControllerReferenceForNamespace(targetNamespace), err
+		if len(reference.Name) == 0 {
+			// Guessing the pod from the other pods in the namespace would reference an unrelated controller, so use the namespace.
+			return getControllerReferenceForNamespace(targetNamespace), fmt.Errorf("unable to setup event recorder as %q env variable is not set", podNameEnv)
 		}
 		return GetControllerReferenceForCurrentPod(ctx, client, targetNamespace, reference)
 	}
@@ -118,32 +160,6 @@ func makeObjectReference(owner *metav1.OwnerReference, targetNamespace string) *
 	}
 }
 
-// guessControllerReferenceForNamespace tries to guess what resource to reference.
-func guessControllerReferenceForNamespace(ctx context.Context, client corev1client.PodInterface) (*corev1.ObjectReference, error) {
-	pods, err := client.List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, err
-	}
-	if len(pods.Items) == 0 {
-		return nil, fmt.Errorf("unable to setup event recorder as %q env variable is not set and there are no pods", podNameEnv)
-	}
-
-	for _, pod := range pods.Items {
-		ownerRef := metav1.GetControllerOf(&pod)
-		if ownerRef == nil {
-			continue
-		}
-		return &corev1.ObjectReference{
-			Kind:       ownerRef.Kind,
-			Namespace:  pod.Namespace,
-			Name:       ownerRef.Name,
-			UID:        ownerRef.UID,
-			APIVersion: ownerRef.APIVersion,
-		}, nil
-	}
-	return nil, errors.New("can't guess controller ref")
-}
-
 // NewRecorder returns new event recorder.
 func NewRecorder(client corev1client.EventInterface, sourceComponentName string, involvedObjectRef *corev1.ObjectReference) Recorder {
 	return &recorder{
@@ -158,6 +174,7 @@ type recorder struct {
 	eventClient       corev1client.EventInterface
 	involvedObjectRef *corev1.ObjectReference
 	sourceComponent   string
+	metadata          eventMetadata
 
 	// TODO: This is not the right way to pass the context, but there is no other way without breaking event interface
 	ctx context.Context
@@ -176,8 +193,21 @@ func (r *recorder) ForComponent(componentName string) Recorder {
 }
 
 func (r *recorder) WithContext(ctx context.Context) Recorder {
-	r.ctx = ctx
-	return r
+	newRecorder := *r
+	newRecorder.ctx = ctx
+	return &newRecorder
+}
+
+func (r *recorder) WithLabels(labels map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withLabels(labels)
+	return &newRecorder
+}
+
+func (r *recorder) WithAnnotations(annotations map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withAnnotations(annotations)
+	return &newRecorder
 }
 
 func (r *recorder) WithComponentSuffix(suffix string) Recorder {
@@ -196,7 +226,7 @@ func (r *recorder) Warningf(reason, messageFmt string, args ...interface{}) {
 
 // Event emits the normal type event.
 func (r *recorder) Event(reason, message string) {
-	event := makeEvent(r.involvedObjectRef, r.sourceComponent, corev1.EventTypeNormal, reason, message)
+	event := r.metadata.apply(makeEvent(r.involvedObjectRef, r.sourceComponent, corev1.EventTypeNormal, reason, message))
 	ctx := context.Background()
 	if r.ctx != nil {
 		ctx = r.ctx
@@ -208,7 +238,7 @@ func (r *recorder) Event(reason, message string) {
 
 // Warning emits the warning type event.
 func (r *recorder) Warning(reason, message string) {
-	event := makeEvent(r.involvedObjectRef, r.sourceComponent, corev1.EventTypeWarning, reason, message)
+	event := r.metadata.apply(makeEvent(r.involvedObjectRef, r.sourceComponent, corev1.EventTypeWarning, reason, message))
 	ctx := context.Background()
 	if r.ctx != nil {
 		ctx = r.ctx
@@ -10,12 +10,24 @@ import (
 )
 
 type inMemoryEventRecorder struct {
+	store    *inMemoryEventStore
+	source   string
+	ctx      context.Context
+	metadata eventMetadata
+}
+
+// inMemoryEventStore holds the events recorded by an in-memory recorder and all recorders derived from it.
+type inMemoryEventStore struct {
 	events []*corev1.Event
-	source string
-	ctx    context.Context
 	sync.Mutex
 }
 
+func (s *inMemoryEventStore) append(event *corev1.Event) {
+	s.Lock()
+	defer s.Unlock()
+	s.events = append(s.events, event)
+}
+
 // inMemoryDummyObjectReference is used for fake events.
 var inMemoryDummyObjectReference = corev1.ObjectReference{
 	Kind:       "Pod",
@@ -30,9 +42,10 @@ type InMemoryRecorder interface {
 }
 
 // NewInMemoryRecorder provides event recorder that stores all events recorded in memory and allow to replay them using the Events() method.
+// Recorders derived from this recorder share the same storage, so Events() returns events recorded by all of them.
 // This recorder should be only used in unit tests.
 func NewInMemoryRecorder(sourceComponent string) InMemoryRecorder {
-	return &inMemoryEventRecorder{events: []*corev1.Event{}, source: sourceComponent}
+	return &inMemoryEventRecorder{store: &inMemoryEventStore{events: []*corev1.Event{}}, source: sourceComponent}
 }
 
 func (r *inMemoryEventRecorder) ComponentName() string {
@@ -42,31 +55,53 @@ func (r *inMemoryEventRecorder) ComponentName() string {
 func (r *inMemoryEventRecorder) Shutdown() {}
 
 func (r *inMemoryEventRecorder) ForComponent(component string) Recorder {
-	r.Lock()
-	defer r.Unlock()
-	r.source = component
-	return r
+	newRecorder := *r
+	newRecorder.source = component
+	return &newRecorder
 }
 
 func (r *inMemoryEventRecorder) WithContext(ctx context.Context) Recorder {
-	r.ctx = ctx
-	return r
+	newRecorder := *r
+	newRecorder.ctx = ctx
+	return &newRecorder
+}
+
+func (r *inMemoryEventRecorder) WithLabels(labels map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withLabels(labels)
+	return &newRecorder
+}
+
+func (r *inMemoryEventRecorder) WithAnnotations(annotations map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withAnnotations(annotations)
+	return &newRecorder
 }
 
 func (r *inMemoryEventRecorder) WithComponentSuffix(suffix string) Recorder {
 	return r.ForComponent(fmt.Sprintf("%s-%s", r.ComponentName(), suffix))
 }
 
-// Events returns list of recorded events
+// Events returns a copy of the list of recorded events.
+// The returned slice is safe to use while other goroutines keep recording events.
 func (r *inMemoryEventRecorder) Events() []*corev1.Event {
-	return r.events
+	r.store.Lock()
+	defer r.store.Unlock()
+	events := make([]*corev1.Event, 0, len(r.store.events))
+	for _, event := range r.store.events {
+		events = append(events, event.DeepCopy())
+	}
+	return events
+}
+
+// appendEvent stores already constructed event (used when replaying events from a file).
+func (r *inMemoryEventRecorder) appendEvent(event *corev1.Event) {
+	r.store.append(event)
 }
 
 func (r *inMemoryEventRecorder) Event(reason, message string) {
-	r.Lock()
-	defer r.Unlock()
-	event := makeEvent(&inMemoryDummyObjectReference, r.source, corev1.EventTypeNormal, reason, message)
-	r.events = append(r.events, event)
+	event := r.metadata.apply(makeEvent(&inMemoryDummyObjectReference, r.source, corev1.EventTypeNormal, reason, message))
+	r.store.append(event)
 }
 
 func (r *inMemoryEventRecorder) Eventf(reason, messageFmt string, args ...interface{}) {
@@ -74,11 +109,9 @@ func (r *inMemoryEventRecorder) Eventf(reason, messageFmt string, args ...interf
 }
 
 func (r *inMemoryEventRecorder) Warning(reason, message string) {
-	r.Lock()
-	defer r.Unlock()
-	event := makeEvent(&inMemoryDummyObjectReference, r.source, corev1.EventTypeWarning, reason, message)
+	event := r.metadata.apply(makeEvent(&inMemoryDummyObjectReference, r.source, corev1.EventTypeWarning, reason, message))
 	klog.Info(event.String())
-	r.events = append(r.events, event)
+	r.store.append(event)
 }
 
 func (r *inMemoryEventRecorder) Warningf(reason, messageFmt string, args ...interface{}) {
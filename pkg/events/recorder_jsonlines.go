@@ -0,0 +1,322 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// JSONLinesEvent is a single event record written by the JSON lines recorder.
+type JSONLinesEvent struct {
+	Timestamp      time.Time              `json:"timestamp"`
+	Component      string                 `json:"component"`
+	Type           string                 `json:"type"`
+	Reason         string                 `json:"reason"`
+	Message        string                 `json:"message"`
+	InvolvedObject corev1.ObjectReference `json:"involvedObject"`
+	Labels         map[string]string      `json:"labels,omitempty"`
+	Annotations    map[string]string      `json:"annotations,omitempty"`
+}
+
+// jsonLinesSink serialize writes from all recorders derived from the same JSON lines recorder.
+type jsonLinesSink struct {
+	writer io.Writer
+	sync.Mutex
+}
+
+func (s *jsonLinesSink) write(record *JSONLinesEvent) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	_, err = s.writer.Write(append(data, '\n'))
+	return err
+}
+
+type jsonLinesRecorder struct {
+	sink              *jsonLinesSink
+	component         string
+	involvedObjectRef *corev1.ObjectReference
+	metadata          eventMetadata
+	ctx               context.Context
+}
+
+// NewJSONLinesRecorder provides event recorder that writes every event as a single JSON line into the given writer.
+// This is useful for offline audit or debugging in air-gapped environments where the events are not available in the cluster.
+// Use NewRotatingFileWriter to write the events into a file with size based rotation.
+// The caller is responsible for closing the writer after the recorder is not used anymore.
+func NewJSONLinesRecorder(writer io.Writer, component string, involvedObjectRef *corev1.ObjectReference) Recorder {
+	if involvedObjectRef == nil {
+		involvedObjectRef = &inMemoryDummyObjectReference
+	}
+	return &jsonLinesRecorder{
+		sink:              &jsonLinesSink{writer: writer},
+		component:         component,
+		involvedObjectRef: involvedObjectRef,
+	}
+}
+
+func (r *jsonLinesRecorder) ComponentName() string {
+	return r.component
+}
+
+func (r *jsonLinesRecorder) Shutdown() {}
+
+func (r *jsonLinesRecorder) ForComponent(component string) Recorder {
+	newRecorder := *r
+	newRecorder.component = component
+	return &newRecorder
+}
+
+func (r *jsonLinesRecorder) WithComponentSuffix(suffix string) Recorder {
+	return r.ForComponent(fmt.Sprintf("%s-%s", r.ComponentName(), suffix))
+}
+
+func (r *jsonLinesRecorder) WithContext(ctx context.Context) Recorder {
+	newRecorder := *r
+	newRecorder.ctx = ctx
+	return &newRecorder
+}
+
+func (r *jsonLinesRecorder) WithLabels(labels map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withLabels(labels)
+	return &newRecorder
+}
+
+func (r *jsonLinesRecorder) WithAnnotations(annotations map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withAnnotations(annotations)
+	return &newRecorder
+}
+
+func (r *jsonLinesRecorder) Event(reason, message string) {
+	r.write(corev1.EventTypeNormal, reason, message)
+}
+
+func (r *jsonLinesRecorder) Eventf(reason, messageFmt string, args ...interface{}) {
+	r.Event(reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *jsonLinesRecorder) Warning(reason, message string) {
+	r.write(corev1.EventTypeWarning, reason, message)
+}
+
+func (r *jsonLinesRecorder) Warningf(reason, messageFmt string, args ...interface{}) {
+	r.Warning(reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *jsonLinesRecorder) write(eventType, reason, message string) {
+	record := &JSONLinesEvent{
+		Timestamp:      time.Now(),
+		Component:      r.component,
+		Type:           eventType,
+		Reason:         reason,
+		Message:        message,
+		InvolvedObject: *r.involvedObjectRef,
+		Labels:         r.metadata.labels,
+		Annotations:    r.metadata.annotations,
+	}
+	if err := r.sink.write(record); err != nil {
+		klog.Warningf("Error writing event %s/%s %q: %v", eventType, reason, message, err)
+	}
+}
+
+// ReadJSONLines reads the events written by JSON lines recorder.
+func ReadJSONLines(reader io.Reader) ([]*corev1.Event, error) {
+	var result []*corev1.Event
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := JSONLinesEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+		event := eventMetadata{labels: record.Labels, annotations: record.Annotations}.apply(
+			makeEvent(&record.InvolvedObject, record.Component, record.Type, record.Reason, record.Message))
+		event.Name = fmt.Sprintf("%v.%x", record.InvolvedObject.Name, record.Timestamp.UnixNano())
+		event.FirstTimestamp = metav1.Time{Time: record.Timestamp}
+		event.LastTimestamp = event.FirstTimestamp
+		result = append(result, event)
+	}
+	return result, scanner.Err()
+}
+
+// ReplayJSONLines reads the events written by JSON lines recorder and replays them into the in-memory recorder.
+// The original timestamps, components and involved objects are preserved, so the in-memory recorder can be used
+// for post-mortem assertions in unit tests.
+func ReplayJSONLines(reader io.Reader, recorder InMemoryRecorder) error {
+	events, err := ReadJSONLines(reader)
+	for _, event := range events {
+		replayEvent(recorder, event)
+	}
+	return err
+}
+
+// ReplayJSONLinesFile replays the events from the given file and its rotated backups (oldest first) into the in-memory recorder.
+func ReplayJSONLinesFile(path string, recorder InMemoryRecorder) error {
+	for _, name := range append(existingBackups(path), path) {
+		if err := replayFile(name, recorder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func replayFile(name string, recorder InMemoryRecorder) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := ReplayJSONLines(f, recorder); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// eventAppender is implemented by recorders that can store fully constructed events.
+type eventAppender interface {
+	appendEvent(event *corev1.Event)
+}
+
+func replayEvent(recorder InMemoryRecorder, event *corev1.Event) {
+	if appender, ok := recorder.(eventAppender); ok {
+		appender.appendEvent(event)
+		return
+	}
+	if event.Type == corev1.EventTypeWarning {
+		recorder.Warning(event.Reason, event.Message)
+		return
+	}
+	recorder.Event(event.Reason, event.Message)
+}
+
+// RotatingFileWriter is a file writer that rotates the file when it reaches the maximum size.
+// The rotated files are named <path>.1 (most recent) up to <path>.<maxBackups> (oldest).
+type RotatingFileWriter struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	file *os.File
+	size int64
+	sync.Mutex
+}
+
+var _ io.WriteCloser = &RotatingFileWriter{}
+
+// NewRotatingFileWriter opens (or creates) the file at given path for appending and return writer that will rotate the file
+// when writing to it would exceed maxBytes. At most maxBackups rotated files are kept.
+func NewRotatingFileWriter(path string, maxBytes int64, maxBackups int) (*RotatingFileWriter, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("maximum file size must be positive, got %d", maxBytes)
+	}
+	w := &RotatingFileWriter{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingFileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write writes p into the file, rotating the file first if the write would exceed the maximum size.
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the underlying file.
+func (w *RotatingFileWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	if w.maxBackups <= 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return w.open()
+	}
+	if err := os.Remove(backupName(w.path, w.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := w.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(w.path, i), backupName(w.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, backupName(w.path, 1)); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+func backupName(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
+
+// existingBackups returns the rotated files for given path ordered from the oldest to the most recent.
+func existingBackups(path string) []string {
+	var backups []string
+	for i := 1; ; i++ {
+		name := backupName(path, i)
+		if _, err := os.Stat(name); err != nil {
+			break
+		}
+		backups = append([]string{name}, backups...)
+	}
+	return backups
+}
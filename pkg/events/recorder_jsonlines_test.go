@@ -0,0 +1,99 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestJSONLinesRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJSONLinesRecorder(&buf, "test-operator", fakeObjectReference).WithComponentSuffix("controller")
+
+	r.Eventf("SecretCreated", "secret %q created", "foo")
+	r.Warning("SecretFailed", "failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+	record := JSONLinesEvent{}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Component != "test-operator-controller" {
+		t.Errorf("expected component test-operator-controller, got %q", record.Component)
+	}
+	if record.Type != corev1.EventTypeWarning || record.Reason != "SecretFailed" || record.Message != "failed" {
+		t.Errorf("unexpected record: %#v", record)
+	}
+	if record.InvolvedObject.Name != fakeObjectReference.Name || record.Timestamp.IsZero() {
+		t.Errorf("expected involved object and timestamp to be set, got %#v", record)
+	}
+
+	replayed := NewInMemoryRecorder("replay")
+	if err := ReplayJSONLines(&buf, replayed); err != nil {
+		t.Fatal(err)
+	}
+	events := replayed.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 replayed events, got %d", len(events))
+	}
+	if events[0].Message != `secret "foo" created` || events[0].Source.Component != "test-operator-controller" {
+		t.Errorf("unexpected replayed event: %#v", events[0])
+	}
+	if events[1].InvolvedObject.Kind != "Deployment" || !events[1].FirstTimestamp.Time.Equal(record.Timestamp) {
+		t.Errorf("expected involved object and timestamp to be preserved, got %#v", events[1])
+	}
+}
+
+func TestRotatingFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	w, err := NewRotatingFileWriter(path, 512, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewJSONLinesRecorder(w, "test", nil)
+	for i := 0; i < 20; i++ {
+		r.Eventf("Test", "event number %d", i)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", name, err)
+		}
+		if info.Size() > 512 {
+			t.Errorf("expected %s to be at most 512 bytes, got %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, got %v", err)
+	}
+
+	replayed := NewInMemoryRecorder("replay")
+	if err := ReplayJSONLinesFile(path, replayed); err != nil {
+		t.Fatal(err)
+	}
+	events := replayed.Events()
+	if len(events) == 0 {
+		t.Fatal("expected events to be replayed")
+	}
+	if last := events[len(events)-1].Message; last != fmt.Sprintf("event number %d", 19) {
+		t.Errorf("expected last replayed event to be the most recent one, got %q", last)
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].FirstTimestamp.Before(&events[i-1].FirstTimestamp) {
+			t.Errorf("expected events to be replayed in order, got %q before %q", events[i-1].Message, events[i].Message)
+		}
+	}
+}
@@ -11,11 +11,25 @@ import (
 type LoggingEventRecorder struct {
 	component string
 	ctx       context.Context
+	metadata  eventMetadata
 }
 
 func (r *LoggingEventRecorder) WithContext(ctx context.Context) Recorder {
-	r.ctx = ctx
-	return r
+	newRecorder := *r
+	newRecorder.ctx = ctx
+	return &newRecorder
+}
+
+func (r *LoggingEventRecorder) WithLabels(labels map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withLabels(labels)
+	return &newRecorder
+}
+
+func (r *LoggingEventRecorder) WithAnnotations(annotations map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withAnnotations(annotations)
+	return &newRecorder
 }
 
 // NewLoggingEventRecorder provides event recorder that will log all recorded events via klog.
@@ -40,7 +54,7 @@ func (r *LoggingEventRecorder) WithComponentSuffix(suffix string) Recorder {
 }
 
 func (r *LoggingEventRecorder) Event(reason, message string) {
-	event := makeEvent(&inMemoryDummyObjectReference, "", corev1.EventTypeNormal, reason, message)
+	event := r.metadata.apply(makeEvent(&inMemoryDummyObjectReference, "", corev1.EventTypeNormal, reason, message))
 	klog.Info(event.String())
 }
 
@@ -49,7 +63,7 @@ func (r *LoggingEventRecorder) Eventf(reason, messageFmt string, args ...interfa
 }
 
 func (r *LoggingEventRecorder) Warning(reason, message string) {
-	event := makeEvent(&inMemoryDummyObjectReference, "", corev1.EventTypeWarning, reason, message)
+	event := r.metadata.apply(makeEvent(&inMemoryDummyObjectReference, "", corev1.EventTypeWarning, reason, message))
 	klog.Warning(event.String())
 }
 
@@ -0,0 +1,271 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/utils/clock"
+)
+
+// EventsSuppressedReason is the reason of the summary event emitted by the rate limited recorder.
+const EventsSuppressedReason = "EventsSuppressed"
+
+// EventPolicy describes how the events matching the type and reason are rate limited and sampled.
+type EventPolicy struct {
+	// Type is the event type (Normal or Warning) this policy applies to. Empty type matches all events.
+	Type string
+	// Reason is the event reason this policy applies to. Empty reason matches all reasons.
+	Reason string
+
+	// Burst is the number of events allowed per Period. If Period is zero, the events are not rate limited.
+	// The events are limited per reason and per recorder derivation (component and labels), so stamping the object
+	// via WithLabels gives per-object limits.
+	// For example, Burst=1 and Period=5m allows at most one event with the reason per object every 5 minutes.
+	Burst  int
+	Period time.Duration
+
+	// SampleRatio is the ratio of events that are emitted (0.01 means 1% of events is emitted).
+	// Zero means the events are not sampled.
+	SampleRatio float64
+}
+
+func (p EventPolicy) matches(eventType, reason string) bool {
+	return (len(p.Type) == 0 || p.Type == eventType) && (len(p.Reason) == 0 || p.Reason == reason)
+}
+
+var suppressedEventsCounterMetric = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "event_recorder",
+	Name:           "suppressed_events_count",
+	Help:           "Total count of events suppressed by rate limiting or sampling",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"severity", "reason"})
+
+func init() {
+	legacyregistry.MustRegister(suppressedEventsCounterMetric)
+}
+
+// rateLimitState is shared by all recorders derived from the same rate limited recorder.
+type rateLimitState struct {
+	policies []EventPolicy
+	clock    clock.WithTicker
+	random   func() float64
+
+	// buckets hold the token buckets per policy, event type, reason, component and labels
+	buckets map[string]*rateLimitBucket
+	// suppressed count the events suppressed since the last summary per event type and reason
+	suppressed map[string]int
+
+	summaryInterval time.Duration
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+	sync.Mutex
+}
+
+type rateLimitBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+	period   time.Duration
+}
+
+type rateLimitedRecorder struct {
+	delegate Recorder
+	// summaryRecorder is the recorder used to emit the summary events
+	summaryRecorder Recorder
+	// labels identify the object the events are about (see EventPolicy.Burst)
+	labels map[string]string
+	state  *rateLimitState
+}
+
+// NewRateLimitedRecorder returns a recorder that rate limits and samples the events according to the given policies
+// before passing them to the delegate recorder. The first policy matching the event type and reason is used and events not
+// matching any policy are passed through.
+// The number of suppressed events is counted in a metric and if summaryInterval is not zero, a summary event with
+// EventsSuppressedReason is emitted periodically when some events were suppressed.
+// Call Shutdown() to stop the periodic summary and to shut down the delegate recorder.
+func NewRateLimitedRecorder(delegate Recorder, summaryInterval time.Duration, policies ...EventPolicy) Recorder {
+	return newRateLimitedRecorder(delegate, summaryInterval, clock.RealClock{}, rand.Float64, policies...)
+}
+
+func newRateLimitedRecorder(delegate Recorder, summaryInterval time.Duration, clock clock.WithTicker, random func() float64, policies ...EventPolicy) *rateLimitedRecorder {
+	state := &rateLimitState{
+		policies:        policies,
+		clock:           clock,
+		random:          random,
+		buckets:         map[string]*rateLimitBucket{},
+		suppressed:      map[string]int{},
+		summaryInterval: summaryInterval,
+		stopCh:          make(chan struct{}),
+	}
+	r := &rateLimitedRecorder{delegate: delegate, summaryRecorder: delegate, state: state}
+	if summaryInterval > 0 {
+		go r.runSummary()
+	}
+	return r
+}
+
+func (r *rateLimitedRecorder) runSummary() {
+	ticker := r.state.clock.NewTicker(r.state.summaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.state.stopCh:
+			return
+		case <-ticker.C():
+			r.emitSummary()
+		}
+	}
+}
+
+// emitSummary emits the summary event about suppressed events and forgets the idle token buckets.
+func (r *rateLimitedRecorder) emitSummary() {
+	r.state.Lock()
+	suppressed := r.state.suppressed
+	r.state.suppressed = map[string]int{}
+	now := r.state.clock.Now()
+	for key, bucket := range r.state.buckets {
+		// the bucket is full again, no need to keep it
+		if now.Sub(bucket.lastSeen) > bucket.period {
+			delete(r.state.buckets, key)
+		}
+	}
+	r.state.Unlock()
+
+	if len(suppressed) == 0 {
+		return
+	}
+	total := 0
+	var details []string
+	for key, count := range suppressed {
+		total += count
+		details = append(details, fmt.Sprintf("%s (%d)", key, count))
+	}
+	sort.Strings(details)
+	r.summaryRecorder.Eventf(EventsSuppressedReason, "Suppressed %d events in the last %s: %s", total, r.state.summaryInterval, strings.Join(details, ", "))
+}
+
+// allow returns true if the event should be passed to the delegate recorder.
+func (r *rateLimitedRecorder) allow(eventType, reason string) bool {
+	r.state.Lock()
+	defer r.state.Unlock()
+	for i, policy := range r.state.policies {
+		if !policy.matches(eventType, reason) {
+			continue
+		}
+		if policy.SampleRatio > 0 && r.state.random() >= policy.SampleRatio {
+			r.suppress(eventType, reason)
+			return false
+		}
+		if policy.Period > 0 && !r.takeToken(i, policy, eventType, reason) {
+			r.suppress(eventType, reason)
+			return false
+		}
+		return true
+	}
+	return true
+}
+
+func (r *rateLimitedRecorder) takeToken(policyIndex int, policy EventPolicy, eventType, reason string) bool {
+	key := strings.Join([]string{fmt.Sprint(policyIndex), eventType, reason, r.delegate.ComponentName(), labelsKey(r.labels)}, "/")
+	now := r.state.clock.Now()
+	bucket, ok := r.state.buckets[key]
+	if !ok {
+		burst := policy.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		bucket = &rateLimitBucket{
+			limiter: rate.NewLimiter(rate.Limit(float64(burst)/policy.Period.Seconds()), burst),
+			period:  policy.Period,
+		}
+		r.state.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+	return bucket.limiter.AllowN(now, 1)
+}
+
+func (r *rateLimitedRecorder) suppress(eventType, reason string) {
+	r.state.suppressed[eventType+"/"+reason]++
+	suppressedEventsCounterMetric.WithLabelValues(eventType, reason).Inc()
+}
+
+func (r *rateLimitedRecorder) derive(delegate Recorder) *rateLimitedRecorder {
+	newRecorder := *r
+	newRecorder.delegate = delegate
+	return &newRecorder
+}
+
+func (r *rateLimitedRecorder) ComponentName() string {
+	return r.delegate.ComponentName()
+}
+
+// Shutdown emits the final summary, stops the periodic summary and shut down the delegate recorder.
+func (r *rateLimitedRecorder) Shutdown() {
+	r.state.stopOnce.Do(func() {
+		close(r.state.stopCh)
+		if r.state.summaryInterval > 0 {
+			r.emitSummary()
+		}
+	})
+	r.delegate.Shutdown()
+}
+
+func (r *rateLimitedRecorder) ForComponent(componentName string) Recorder {
+	return r.derive(r.delegate.ForComponent(componentName))
+}
+
+func (r *rateLimitedRecorder) WithComponentSuffix(suffix string) Recorder {
+	return r.derive(r.delegate.WithComponentSuffix(suffix))
+}
+
+func (r *rateLimitedRecorder) WithContext(ctx context.Context) Recorder {
+	return r.derive(r.delegate.WithContext(ctx))
+}
+
+func (r *rateLimitedRecorder) WithLabels(labels map[string]string) Recorder {
+	newRecorder := r.derive(r.delegate.WithLabels(labels))
+	newRecorder.labels = mergeStringMaps(r.labels, labels)
+	return newRecorder
+}
+
+func (r *rateLimitedRecorder) WithAnnotations(annotations map[string]string) Recorder {
+	return r.derive(r.delegate.WithAnnotations(annotations))
+}
+
+func (r *rateLimitedRecorder) Event(reason, message string) {
+	if r.allow(corev1.EventTypeNormal, reason) {
+		r.delegate.Event(reason, message)
+	}
+}
+
+func (r *rateLimitedRecorder) Eventf(reason, messageFmt string, args ...interface{}) {
+	r.Event(reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *rateLimitedRecorder) Warning(reason, message string) {
+	if r.allow(corev1.EventTypeWarning, reason) {
+		r.delegate.Warning(reason, message)
+	}
+}
+
+func (r *rateLimitedRecorder) Warningf(reason, messageFmt string, args ...interface{}) {
+	r.Warning(reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// labelsKey returns stable string representation of the labels.
+func labelsKey(labels map[string]string) string {
+	var pairs []string
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
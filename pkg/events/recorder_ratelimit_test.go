@@ -0,0 +1,94 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func countEvents(recorder InMemoryRecorder, reason string) int {
+	count := 0
+	for _, e := range recorder.Events() {
+		if e.Reason == reason {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRateLimitedRecorder_PerReasonLimit(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	inMemory := NewInMemoryRecorder("test")
+	r := newRateLimitedRecorder(inMemory, 0, fakeClock, func() float64 { return 0 }, EventPolicy{
+		Type:   corev1.EventTypeWarning,
+		Reason: "Flapping",
+		Burst:  1,
+		Period: 5 * time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		r.Warning("Flapping", "object is flapping")
+		r.Event("Flapping", "normal events are not limited")
+	}
+	objectA := r.WithLabels(map[string]string{"key": "ns/a"})
+	objectB := r.WithLabels(map[string]string{"key": "ns/b"})
+	objectA.Warning("Flapping", "a")
+	objectA.Warning("Flapping", "a")
+	objectB.Warning("Flapping", "b")
+
+	if count := countEvents(inMemory, "Flapping"); count != 3+3 {
+		t.Errorf("expected 3 normal and 3 warning events (one per object), got %d", count)
+	}
+
+	fakeClock.Step(5*time.Minute + time.Second)
+	r.Warning("Flapping", "object is flapping again")
+	if count := countEvents(inMemory, "Flapping"); count != 7 {
+		t.Errorf("expected the warning to be allowed after the period, got %d events", count)
+	}
+}
+
+func TestRateLimitedRecorder_Sampling(t *testing.T) {
+	randomValues := []float64{0.5, 0.005, 0.9, 0.001}
+	inMemory := NewInMemoryRecorder("test")
+	r := newRateLimitedRecorder(inMemory, 0, clocktesting.NewFakeClock(time.Now()), func() float64 {
+		v := randomValues[0]
+		randomValues = randomValues[1:]
+		return v
+	}, EventPolicy{Type: corev1.EventTypeNormal, SampleRatio: 0.01})
+
+	for i := 0; i < 4; i++ {
+		r.Event("Sampled", "sampled")
+	}
+	r.Warning("Sampled", "warnings are not sampled")
+
+	if count := countEvents(inMemory, "Sampled"); count != 3 {
+		t.Errorf("expected 2 sampled normal events and 1 warning, got %d", count)
+	}
+}
+
+func TestRateLimitedRecorder_Summary(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	inMemory := NewInMemoryRecorder("test")
+	r := newRateLimitedRecorder(inMemory, time.Minute, fakeClock, func() float64 { return 0 }, EventPolicy{Reason: "Noisy", Burst: 1, Period: time.Hour})
+	defer r.Shutdown()
+
+	for i := 0; i < 5; i++ {
+		r.Event("Noisy", "noisy")
+	}
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		fakeClock.Step(time.Minute)
+		return countEvents(inMemory, EventsSuppressedReason) > 0, nil
+	}); err != nil {
+		t.Fatalf("expected summary event: %v", err)
+	}
+	for _, e := range inMemory.Events() {
+		if e.Reason == EventsSuppressedReason && !strings.Contains(e.Message, "Suppressed 4 events") {
+			t.Errorf("unexpected summary message: %q", e.Message)
+		}
+	}
+}
@@ -185,3 +185,81 @@ func TestGetControllerReferenceForCurrentPodFallbackNamespace(t *testing.T) {
 		t.Errorf("expected objectReference to be Namespace, got %q", objectReference.GroupVersionKind().String())
 	}
 }
+
+func TestRecorderDerivationsAreIndependent(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	base := NewRecorder(client.CoreV1().Events("test-namespace"), "test-operator", fakeObjectReference)
+
+	labeled := base.WithLabels(map[string]string{"controller": "foo"}).WithAnnotations(map[string]string{"key": "ns/name"})
+	suffixed := base.WithContext(context.TODO()).WithComponentSuffix("bar")
+
+	base.Event("Base", "base")
+	labeled.Event("Labeled", "labeled")
+	suffixed.WithLabels(map[string]string{"controller": "bar"}).Warning("Suffixed", "suffixed")
+
+	if base.ComponentName() != "test-operator" {
+		t.Errorf("expected base component to stay test-operator, got %q", base.ComponentName())
+	}
+
+	created := map[string]*corev1.Event{}
+	for _, action := range client.Actions() {
+		if action.Matches("create", "events") {
+			event := action.(clientgotesting.CreateAction).GetObject().(*corev1.Event)
+			created[event.Reason] = event
+		}
+	}
+	if len(created["Base"].Labels) != 0 || len(created["Base"].Annotations) != 0 {
+		t.Errorf("expected base event without metadata, got %#v", created["Base"].ObjectMeta)
+	}
+	if created["Labeled"].Labels["controller"] != "foo" || created["Labeled"].Annotations["key"] != "ns/name" {
+		t.Errorf("expected labeled event to have labels and annotations, got %#v", created["Labeled"].ObjectMeta)
+	}
+	if created["Suffixed"].Labels["controller"] != "bar" || created["Suffixed"].Source.Component != "test-operator-bar" {
+		t.Errorf("unexpected suffixed event: %#v", created["Suffixed"])
+	}
+}
+
+func TestInMemoryRecorderDerivationsShareEvents(t *testing.T) {
+	base := NewInMemoryRecorder("test")
+	derived := base.ForComponent("other").WithLabels(map[string]string{"key": "value"})
+
+	derived.Event("Derived", "derived")
+	base.Event("Base", "base")
+
+	if base.ComponentName() != "test" {
+		t.Errorf("expected ForComponent to not modify the source recorder, got %q", base.ComponentName())
+	}
+	events := base.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Source.Component != "other" || events[0].Labels["key"] != "value" {
+		t.Errorf("unexpected derived event: %#v", events[0])
+	}
+	if events[1].Source.Component != "test" || len(events[1].Labels) != 0 {
+		t.Errorf("unexpected base event: %#v", events[1])
+	}
+}
+
+func TestGetControllerReferenceForCurrentPodWithoutPodName(t *testing.T) {
+	// the other pod in the namespace must not be used as the reference
+	client := fake.NewSimpleClientset(fakePod("test", "other"), fakeReplicaSet("test", "test"))
+
+	podNameEnvFunc = func() string {
+		return ""
+	}
+	defer func() {
+		podNameEnvFunc = func() string {
+			return "test"
+		}
+	}()
+
+	objectReference, err := GetControllerReferenceForCurrentPod(context.TODO(), client, "test", nil)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if objectReference.GroupVersionKind().String() != "/v1, Kind=Namespace" || objectReference.Name != "test" {
+		t.Errorf("expected objectReference to be test Namespace, got %#v", objectReference)
+	}
+}
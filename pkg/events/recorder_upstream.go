@@ -2,6 +2,7 @@ package events
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
@@ -40,19 +41,51 @@ type upstreamRecorder struct {
 	eventRecorder     record.EventRecorder
 	involvedObjectRef *corev1.ObjectReference
 	options           record.CorrelatorOptions
+	metadata          eventMetadata
 
-	// shuttingDown indicates that the broadcaster for this recorder is being shut down
-	shuttingDown  bool
-	shutdownMutex sync.RWMutex
+	// shutdown is shared by all recorders using the same broadcaster
+	shutdown *upstreamShutdownState
 
 	// fallbackRecorder is used when the kube recorder is shutting down
 	// in that case we create the events directly.
 	fallbackRecorder Recorder
 }
 
+// upstreamShutdownState tracks whether the broadcaster is being shut down.
+type upstreamShutdownState struct {
+	// shuttingDown indicates that the broadcaster for this recorder is being shut down
+	shuttingDown bool
+	sync.RWMutex
+}
+
+func (s *upstreamShutdownState) isShuttingDown() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.shuttingDown
+}
+
 func (r *upstreamRecorder) WithContext(ctx context.Context) Recorder {
-	r.clientCtx = ctx
-	return r
+	newRecorder := *r
+	newRecorder.clientCtx = ctx
+	newRecorder.fallbackRecorder = r.fallbackRecorder.WithContext(ctx)
+	return &newRecorder
+}
+
+// WithLabels returns a recorder that stamps the labels on every emitted event.
+// The upstream event broadcaster does not support event labels, so the labels are passed to the event sink in the
+// eventLabelsAnnotation annotation and the sink moves them to the event labels before the event is created.
+func (r *upstreamRecorder) WithLabels(labels map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withLabels(labels)
+	newRecorder.fallbackRecorder = r.fallbackRecorder.WithLabels(labels)
+	return &newRecorder
+}
+
+func (r *upstreamRecorder) WithAnnotations(annotations map[string]string) Recorder {
+	newRecorder := *r
+	newRecorder.metadata = r.metadata.withAnnotations(annotations)
+	newRecorder.fallbackRecorder = r.fallbackRecorder.WithAnnotations(annotations)
+	return &newRecorder
 }
 
 // RecommendedClusterSingletonCorrelatorOptions provides recommended event correlator options for components that produce
@@ -93,18 +126,24 @@ func init() {
 }
 
 func (r *upstreamRecorder) ForComponent(componentName string) Recorder {
+	shuttingDown := false
+	if r.shutdown != nil {
+		shuttingDown = r.shutdown.isShuttingDown()
+	}
 	newRecorderForComponent := upstreamRecorder{
 		client:            r.client,
+		clientCtx:         r.clientCtx,
 		fallbackRecorder:  r.fallbackRecorder.WithComponentSuffix(componentName),
 		options:           r.options,
 		involvedObjectRef: r.involvedObjectRef,
-		shuttingDown:      r.shuttingDown,
+		metadata:          r.metadata,
+		shutdown:          &upstreamShutdownState{shuttingDown: shuttingDown},
 	}
 
 	// tweak the event correlator, so we don't loose important events.
 	broadcaster := record.NewBroadcasterWithCorrelatorOptions(r.options)
 	broadcaster.StartLogging(klog.Infof)
-	broadcaster.StartRecordingToSink(&corev1client.EventSinkImpl{Interface: newRecorderForComponent.client})
+	broadcaster.StartRecordingToSink(&labelingEventSink{EventSink: &corev1client.EventSinkImpl{Interface: newRecorderForComponent.client}})
 
 	newRecorderForComponent.eventRecorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: componentName})
 	newRecorderForComponent.broadcaster = broadcaster
@@ -114,9 +153,9 @@ func (r *upstreamRecorder) ForComponent(componentName string) Recorder {
 }
 
 func (r *upstreamRecorder) Shutdown() {
-	r.shutdownMutex.Lock()
-	r.shuttingDown = true
-	r.shutdownMutex.Unlock()
+	r.shutdown.Lock()
+	r.shutdown.shuttingDown = true
+	r.shutdown.Unlock()
 	// Wait for broadcaster to flush events (this is blocking)
 	// TODO: There is still race condition in upstream that might cause panic() on events recorded after the shutdown
 	//       is called as the event recording is not-blocking (go routine based).
@@ -150,24 +189,82 @@ func (r *upstreamRecorder) incrementEventsCounter(severity string) {
 
 // Event emits the normal type event.
 func (r *upstreamRecorder) Event(reason, message string) {
-	r.shutdownMutex.RLock()
-	defer r.shutdownMutex.RUnlock()
+	r.shutdown.RLock()
+	defer r.shutdown.RUnlock()
 	defer r.incrementEventsCounter(corev1.EventTypeNormal)
-	if r.shuttingDown {
+	if r.shutdown.shuttingDown {
 		r.fallbackRecorder.Event(reason, message)
 		return
 	}
-	r.eventRecorder.Event(r.involvedObjectRef, corev1.EventTypeNormal, reason, message)
+	r.record(corev1.EventTypeNormal, reason, message)
 }
 
 // Warning emits the warning type event.
 func (r *upstreamRecorder) Warning(reason, message string) {
-	r.shutdownMutex.RLock()
-	defer r.shutdownMutex.RUnlock()
+	r.shutdown.RLock()
+	defer r.shutdown.RUnlock()
 	defer r.incrementEventsCounter(corev1.EventTypeWarning)
-	if r.shuttingDown {
+	if r.shutdown.shuttingDown {
 		r.fallbackRecorder.Warning(reason, message)
 		return
 	}
-	r.eventRecorder.Event(r.involvedObjectRef, corev1.EventTypeWarning, reason, message)
+	r.record(corev1.EventTypeWarning, reason, message)
+}
+
+func (r *upstreamRecorder) record(eventType, reason, message string) {
+	annotations := r.metadata.annotations
+	if len(r.metadata.labels) > 0 {
+		labels, err := json.Marshal(r.metadata.labels)
+		if err != nil {
+			klog.Warningf("Failed to encode labels of %q event: %v", reason, err)
+		} else {
+			annotations = mergeStringMaps(annotations, map[string]string{eventLabelsAnnotation: string(labels)})
+		}
+	}
+	if len(annotations) > 0 {
+		r.eventRecorder.AnnotatedEventf(r.involvedObjectRef, annotations, eventType, reason, "%s", message)
+		return
+	}
+	r.eventRecorder.Event(r.involvedObjectRef, eventType, reason, message)
+}
+
+// eventLabelsAnnotation passes the labels of the event recorded via the upstream broadcaster to the event sink.
+// The annotation is removed from the event before it is sent to the API server.
+const eventLabelsAnnotation = "events.controller-framework.mfojtik.github.io/labels"
+
+// labelingEventSink moves the labels passed in the eventLabelsAnnotation annotation to the event labels.
+type labelingEventSink struct {
+	record.EventSink
+}
+
+func (s *labelingEventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	return s.EventSink.Create(withEventLabels(event))
+}
+
+func (s *labelingEventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	return s.EventSink.Update(withEventLabels(event))
+}
+
+func (s *labelingEventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	return s.EventSink.Patch(withEventLabels(event), data)
+}
+
+// withEventLabels returns a copy of the event with the labels from the eventLabelsAnnotation annotation.
+func withEventLabels(event *corev1.Event) *corev1.Event {
+	encoded, ok := event.Annotations[eventLabelsAnnotation]
+	if !ok {
+		return event
+	}
+	event = event.DeepCopy()
+	delete(event.Annotations, eventLabelsAnnotation)
+	if len(event.Annotations) == 0 {
+		event.Annotations = nil
+	}
+	labels := map[string]string{}
+	if err := json.Unmarshal([]byte(encoded), &labels); err != nil {
+		klog.Warningf("Failed to decode labels of %q event: %v", event.Reason, err)
+		return event
+	}
+	event.Labels = mergeStringMaps(event.Labels, labels)
+	return event
 }
@@ -9,6 +9,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/record"
 )
@@ -139,3 +140,46 @@ func TestUpstreamRecorder_Shutdown(t *testing.T) {
 	}
 }
 */
+
+func TestUpstreamRecorder_WithAnnotations(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	base := NewKubeRecorder(client.CoreV1().Events("operator-namespace"), "test", fakeObjectReference)
+	base.WithAnnotations(map[string]string{"controller": "foo"}).Event("Annotated", "annotated")
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		events, err := client.CoreV1().Events("operator-namespace").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		return len(events.Items) == 1, nil
+	}); err != nil {
+		t.Fatalf("expected event to be created: %v", err)
+	}
+	events, _ := client.CoreV1().Events("operator-namespace").List(context.TODO(), metav1.ListOptions{})
+	if events.Items[0].Annotations["controller"] != "foo" {
+		t.Errorf("expected event to be annotated, got %#v", events.Items[0].Annotations)
+	}
+}
+
+func TestUpstreamRecorder_WithLabels(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	base := NewKubeRecorder(client.CoreV1().Events("operator-namespace"), "test", fakeObjectReference)
+	base.WithLabels(map[string]string{"app": "foo"}).WithAnnotations(map[string]string{"controller": "foo"}).Event("Labeled", "labeled")
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		events, err := client.CoreV1().Events("operator-namespace").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		return len(events.Items) == 1, nil
+	}); err != nil {
+		t.Fatalf("expected event to be created: %v", err)
+	}
+	events, _ := client.CoreV1().Events("operator-namespace").List(context.TODO(), metav1.ListOptions{})
+	if events.Items[0].Labels["app"] != "foo" {
+		t.Errorf("expected event to be labeled, got %#v", events.Items[0].Labels)
+	}
+	if _, ok := events.Items[0].Annotations[eventLabelsAnnotation]; ok || events.Items[0].Annotations["controller"] != "foo" {
+		t.Errorf("expected only the controller annotation, got %#v", events.Items[0].Annotations)
+	}
+}
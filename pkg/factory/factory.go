@@ -1,18 +1,24 @@
 package factory
 
 import (
+	gocontext "context"
 	"fmt"
 	"github.com/mfojtik/controller-framework/pkg/context"
 	"github.com/mfojtik/controller-framework/pkg/controller"
 	"github.com/mfojtik/controller-framework/pkg/framework"
+	"github.com/mfojtik/controller-framework/pkg/queue"
 	corev1 "k8s.io/api/core/v1"
+	"sort"
 	"time"
 
-	"github.com/robfig/cron"
 	"k8s.io/apimachinery/pkg/runtime"
 	errorutil "k8s.io/apimachinery/pkg/util/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/mfojtik/controller-framework/pkg/events"
 	//operatorv1helpers "github.com/mfojtik/controller-framework/pkg/operator/v1helpers"
@@ -31,37 +37,71 @@ type Factory struct {
 	sync        framework.ControllerSyncFn
 	syncContext framework.Context
 
+	batchSync       framework.ControllerBatchSyncFn
+	batchMaxSize    int
+	batchMaxLatency time.Duration
+
 	//syncDegradedClient    operatorv1helpers.OperatorClient
 	resyncInterval  time.Duration
-	resyncSchedules []string
-
-	informers          []filteredInformers
-	informerQueueKeys  []informersWithQueueKey
-	bareInformers      []framework.Informer
-	namespaceInformers []*namespaceInformer
-	cachesToSync       []cache.InformerSynced
+	resyncOptions   resyncOptions
+	resyncSchedules []resyncSchedule
+	scheduleStore   framework.ScheduleStore
+	clock           clock.WithTicker
+
+	workerAutoscalingPolicy *framework.WorkerAutoscalingPolicy
+	// newQueue creates the controller queue, if not set the default rate limiting queue is used
+	newQueue func(name string) workqueue.RateLimitingInterface
+	// queueKinds are the names of the methods that set newQueue, used to detect the conflicting queues
+	queueKinds sets.Set[string]
+
+	informers           []filteredInformers
+	informerQueueKeys   []informersWithQueueKey
+	bareInformers       []framework.Informer
+	namespaceInformers  []*namespaceInformer
+	cachesToSync        []cache.InformerSynced
+	debouncedInformers  []debouncedInformers
+	indexedReferences   []indexedReferences
+	resourceInformers   []InformerResource
+	namespaceSelections []namespaceSelection
+	sharedInformers     *SharedInformers
 
 	postStartHooks        []framework.PostStartHook
 	interestingNamespaces sets.Set[string]
+	cacheSyncTimeout      time.Duration
 
 	controllerPanicHandler framework.ControllerSyncPanicFn
 	controllerErrorHandler framework.ControllerSyncErrorFn
 }
 
+type resyncSchedule struct {
+	name            string
+	spec            string
+	queueKeyFn      framework.ScheduleQueueKeyFunc
+	missedRunPolicy framework.MissedRunPolicy
+}
+
 type namespaceInformer struct {
-	informer framework.Informer
-	nsFilter framework.EventFilterFunc
+	informer  framework.Informer
+	predicate framework.Predicate
 }
 
 type informersWithQueueKey struct {
 	informers  []framework.Informer
-	filter     framework.EventFilterFunc
+	predicate  framework.Predicate
 	queueKeyFn framework.ObjectQueueKeysFunc
+	// priority is the priority of the keys added by informers, used only with priority queue
+	priority *int
+}
+
+type debouncedInformers struct {
+	informers []framework.Informer
+	window    time.Duration
+	maxWait   time.Duration
 }
 
 type filteredInformers struct {
 	informers []framework.Informer
-	filter    framework.EventFilterFunc
+	predicate framework.Predicate
 }
 
 // ObjectQueueKeyFunc is used to make a string work queue key out of the runtime object that is passed to it.
@@ -72,7 +112,7 @@ type ObjectQueueKeyFunc func(runtime.Object) string
 
 // New return new factory instance.
 func New() *Factory {
-	return &Factory{}
+	return &Factory{queueKinds: sets.New[string](), cacheSyncTimeout: defaultCacheSyncTimeout}
 }
 
 // WithSync is used to set the controller synchronization function. This function is the core of the controller and is
@@ -82,6 +122,18 @@ func (f *Factory) WithSync(syncFn framework.ControllerSyncFn) *Factory {
 	return f
 }
 
+// WithBatchSync is used to set the controller batch synchronization function, use it instead of WithSync for controllers
+// that are more efficient when they reconcile many keys at once (eg. a single API call to external system).
+// The workers collect up to maxBatchSize keys, waiting at most maxLatency for more keys after the first one arrives, and
+// call the batch sync function with all of them. The keys that failed to sync are requeued with rate limiting individually.
+// The Sync() method of the controller calls the batch sync function with the single key from the controller context.
+func (f *Factory) WithBatchSync(batchSyncFn framework.ControllerBatchSyncFn, maxBatchSize int, maxLatency time.Duration) *Factory {
+	f.batchSync = batchSyncFn
+	f.batchMaxSize = maxBatchSize
+	f.batchMaxLatency = maxLatency
+	return f
+}
+
 // WithInformers is used to register event handlers and get the caches synchronized functions.
 // Pass informers you want to use to react to changes on resources. If informer event is observed, then the Sync() function
 // is called.
@@ -95,9 +147,15 @@ func (f *Factory) WithInformers(informers ...framework.Informer) *Factory {
 // is called.
 // Pass filter to filter out events that should not trigger Sync() call.
 func (f *Factory) WithFilteredEventsInformers(filter framework.EventFilterFunc, informers ...framework.Informer) *Factory {
+	return f.WithPredicatedInformers(filterPredicate(filter), informers...)
+}
+
+// WithPredicatedInformers is like WithFilteredEventsInformers, but the events are filtered by the predicate, which receives
+// both old and new object on update, see framework.Predicate.
+func (f *Factory) WithPredicatedInformers(predicate framework.Predicate, informers ...framework.Informer) *Factory {
 	f.informers = append(f.informers, filteredInformers{
 		informers: informers,
-		filter:    filter,
+		predicate: predicate,
 	})
 	return f
 }
@@ -132,9 +190,15 @@ func (f *Factory) WithInformersQueueKeyFunc(queueKeyFn ObjectQueueKeyFunc, infor
 // Pass the queueKeyFn you want to use to transform the informer runtime.Object into string key used by work queue.
 // Pass filter to filter out events that should not trigger Sync() call.
 func (f *Factory) WithFilteredEventsInformersQueueKeyFunc(queueKeyFn ObjectQueueKeyFunc, filter framework.EventFilterFunc, informers ...framework.Informer) *Factory {
+	return f.WithPredicatedInformersQueueKeyFunc(queueKeyFn, filterPredicate(filter), informers...)
+}
+
+// WithPredicatedInformersQueueKeyFunc is like WithFilteredEventsInformersQueueKeyFunc, but the events are filtered by the
+// predicate, see framework.Predicate.
+func (f *Factory) WithPredicatedInformersQueueKeyFunc(queueKeyFn ObjectQueueKeyFunc, predicate framework.Predicate, informers ...framework.Informer) *Factory {
 	f.informerQueueKeys = append(f.informerQueueKeys, informersWithQueueKey{
 		informers: informers,
-		filter:    filter,
+		predicate: predicate,
 		queueKeyFn: func(o runtime.Object) []string {
 			return []string{queueKeyFn(o)}
 		},
@@ -160,10 +224,74 @@ func (f *Factory) WithInformersQueueKeysFunc(queueKeyFn framework.ObjectQueueKey
 // Pass the queueKeyFn you want to use to transform the informer runtime.Object into string key used by work queue.
 // Pass filter to filter out events that should not trigger Sync() call.
 func (f *Factory) WithFilteredEventsInformersQueueKeysFunc(queueKeyFn framework.ObjectQueueKeysFunc, filter framework.EventFilterFunc, informers ...framework.Informer) *Factory {
+	return f.WithPredicatedInformersQueueKeysFunc(queueKeyFn, filterPredicate(filter), informers...)
+}
+
+// WithPredicatedInformersQueueKeysFunc is like WithFilteredEventsInformersQueueKeysFunc, but the events are filtered by the
+// predicate, see framework.Predicate.
+func (f *Factory) WithPredicatedInformersQueueKeysFunc(queueKeyFn framework.ObjectQueueKeysFunc, predicate framework.Predicate, informers ...framework.Informer) *Factory {
+	f.informerQueueKeys = append(f.informerQueueKeys, informersWithQueueKey{
+		informers:  informers,
+		predicate:  predicate,
+		queueKeyFn: queueKeyFn,
+	})
+	return f
+}
+
+// WithPrioritizedInformersQueueKeysFunc is like WithPredicatedInformersQueueKeysFunc, but the keys are added to the queue with
+// the given priority. This allows to process the keys produced by these informers (eg. user facing resources) before
+// other keys. The priority is only respected when the controller uses priority queue (see WithPriorityQueue).
+func (f *Factory) WithPrioritizedInformersQueueKeysFunc(priority int, queueKeyFn framework.ObjectQueueKeysFunc, predicate framework.Predicate, informers ...framework.Informer) *Factory {
 	f.informerQueueKeys = append(f.informerQueueKeys, informersWithQueueKey{
 		informers:  informers,
-		filter:     filter,
+		predicate:  predicate,
 		queueKeyFn: queueKeyFn,
+		priority:   &priority,
+	})
+	return f
+}
+
+// WithPriorityQueue makes the controller use the priority queue. The keys with higher priority are processed first, the keys
+// added by the periodic resync have queue.PriorityLow priority and the keys added by schedules and informers have
+// queue.PriorityNormal priority unless the config PriorityFunc says otherwise.
+// The priority queue cannot be used with a custom sync context set via WithSyncContext.
+func (f *Factory) WithPriorityQueue(config queue.PriorityQueueConfig) *Factory {
+	f.queueKinds = f.queueKinds.Insert("WithPriorityQueue()")
+	f.newQueue = func(name string) workqueue.RateLimitingInterface {
+		if len(config.Name) == 0 {
+			config.Name = name
+		}
+		return queue.NewPriorityQueue(config)
+	}
+	return f
+}
+
+// WithFairQueue makes the controller use the fair queue. The queue keys are partitioned by tenant (the namespace by default)
+// and the tenants are served in round-robin fashion, so a tenant with many objects does not starve the others.
+// The fair queue cannot be used with a custom sync context set via WithSyncContext.
+// WithFairQueue and WithPriorityQueue are mutually exclusive.
+func (f *Factory) WithFairQueue(config queue.FairQueueConfig) *Factory {
+	f.queueKinds = f.queueKinds.Insert("WithFairQueue()")
+	f.newQueue = func(name string) workqueue.RateLimitingInterface {
+		if len(config.Name) == 0 {
+			config.Name = name
+		}
+		return queue.NewFairQueue(config)
+	}
+	return f
+}
+
+// WithInformerDebounce delays adding the keys produced by events of the given informers to the queue until there are no new
+// events for the key for the window duration, but no longer than maxWait since the first event (defaults to ten times the window).
+// The events observed for the key while it is delayed are coalesced into a single sync. This is useful for informers
+// that produce bursts of events (eg. a rollout touching hundreds of pods).
+// The informers must be registered via one of the WithInformers* methods as well, the order of the calls does not matter.
+// The number of coalesced events is reported by the controller_coalesced_events_total metric.
+func (f *Factory) WithInformerDebounce(window, maxWait time.Duration, informers ...framework.Informer) *Factory {
+	f.debouncedInformers = append(f.debouncedInformers, debouncedInformers{
+		informers: informers,
+		window:    window,
+		maxWait:   maxWait,
 	})
 	return f
 }
@@ -178,17 +306,52 @@ func (f *Factory) WithPostStartHooks(hooks ...framework.PostStartHook) *Factory
 // The sync function will only trigger when the object observed by this informer is a namespace and its name matches the interestingNamespaces.
 // Do not use this to register non-namespace informers.
 func (f *Factory) WithNamespaceInformer(informer framework.Informer, interestingNamespaces ...string) *Factory {
+	return f.WithPredicatedNamespaceInformer(informer, nil, interestingNamespaces...)
+}
+
+// WithFilteredEventsNamespaceInformer is like WithNamespaceInformer, but the events of the interesting namespaces are also
+// filtered by the filter.
+func (f *Factory) WithFilteredEventsNamespaceInformer(informer framework.Informer, filter framework.EventFilterFunc, interestingNamespaces ...string) *Factory {
+	return f.WithPredicatedNamespaceInformer(informer, filterPredicate(filter), interestingNamespaces...)
+}
+
+// WithPredicatedNamespaceInformer is like WithNamespaceInformer, but the events of the interesting namespaces are also
+// filtered by the predicate, see framework.Predicate.
+func (f *Factory) WithPredicatedNamespaceInformer(informer framework.Informer, predicate framework.Predicate, interestingNamespaces ...string) *Factory {
+	nsFilter := namespaceChecker(interestingNamespaces)
+	if predicate == nil {
+		f.namespaceInformers = append(f.namespaceInformers, &namespaceInformer{informer: informer, predicate: nsFilter})
+		return f
+	}
 	f.namespaceInformers = append(f.namespaceInformers, &namespaceInformer{
 		informer: informer,
-		nsFilter: namespaceChecker(interestingNamespaces),
+		predicate: framework.PredicateFuncs{
+			CreateFunc: func(obj interface{}) bool {
+				return nsFilter(obj) && predicate.Create(obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) bool {
+				return nsFilter(newObj) && predicate.Update(oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) bool {
+				return nsFilter(obj) && predicate.Delete(obj)
+			},
+		},
 	})
 	return f
 }
 
+// filterPredicate returns the filter as the predicate, the nil filter is returned as nil predicate accepting all events.
+func filterPredicate(filter framework.EventFilterFunc) framework.Predicate {
+	if filter == nil {
+		return nil
+	}
+	return filter
+}
+
 // namespaceChecker returns a function which returns true if an inpuut obj
 // (or its tombstone) is a namespace  and it matches a name of any namespaces
 // that we are interested in
-func namespaceChecker(interestingNamespaces []string) func(obj interface{}) bool {
+func namespaceChecker(interestingNamespaces []string) framework.EventFilterFunc {
 	interestingNamespacesSet := sets.NewString(interestingNamespaces...)
 
 	return func(obj interface{}) bool {
@@ -211,27 +374,171 @@ func namespaceChecker(interestingNamespaces []string) func(obj interface{}) bool
 // ResyncEvery will cause the Sync() function to be called periodically, regardless of informers.
 // This is useful when you want to refresh every N minutes or you fear that your informers can be stucked.
 // If this is not called, no periodical resync will happen.
+// Pass ResyncJitter and ResyncInitialDelay options to prevent resyncs of multiple controllers lining up.
 // Note: The controller context passed to Sync() function in this case does not contain the object metadata or object itself.
 //
 //	This can be used to detect periodical resyncs, but normal Sync() have to be cautious about `nil` objects.
-func (f *Factory) ResyncEvery(interval time.Duration) *Factory {
+func (f *Factory) ResyncEvery(interval time.Duration, options ...ResyncOption) *Factory {
 	f.resyncInterval = interval
+	for _, option := range options {
+		option(&f.resyncOptions)
+	}
 	return f
 }
 
+type resyncOptions struct {
+	jitterFactor float64
+	initialDelay time.Duration
+	fullResync   bool
+	spread       bool
+}
+
+// ResyncOption configures the periodic resync set by ResyncEvery.
+type ResyncOption func(*resyncOptions)
+
+// ResyncJitter randomizes the resync interval, each resync is delayed by up to jitterFactor*interval.
+// For example, ResyncEvery(10*time.Minute, ResyncJitter(0.1)) resyncs every 10 to 11 minutes.
+func ResyncJitter(jitterFactor float64) ResyncOption {
+	return func(o *resyncOptions) {
+		o.jitterFactor = jitterFactor
+	}
+}
+
+// FullResync makes the periodic resync enqueue the keys of all objects in the registered informers' stores instead of
+// the DefaultQueueKey. Every object is passed through the queue keys function and the Create of the predicate the informer was registered with.
+// This is useful for controllers that reconcile individual objects (eg. registered via WithInformersQueueKeysFunc).
+// Informers that do not provide the store (GetStore() method) are not resynced.
+func FullResync() ResyncOption {
+	return func(o *resyncOptions) {
+		o.fullResync = true
+	}
+}
+
+// ResyncSpread spreads the keys enqueued by FullResync evenly across the resync interval to smooth the load.
+func ResyncSpread() ResyncOption {
+	return func(o *resyncOptions) {
+		o.spread = true
+	}
+}
+
+// ResyncInitialDelay delays the first periodic resync. Without the delay, the first resync happens when the controller starts.
+func ResyncInitialDelay(delay time.Duration) ResyncOption {
+	return func(o *resyncOptions) {
+		o.initialDelay = delay
+	}
+}
+
 // ResyncSchedule allows to supply a Cron syntax schedule that will be used to schedule the sync() call runs.
 // This allows more fine-tuned controller scheduling than ResyncEvery.
+// The schedules are validated when the controller is created, see ParseSchedule for the supported syntax.
 // Examples:
 //
-// factory.New().ResyncSchedule("@every 1s").ToController()     // Every second
-// factory.New().ResyncSchedule("@hourly").ToController()       // Every hour
-// factory.New().ResyncSchedule("30 * * * *").ToController()	// Every hour on the half hour
+// factory.New().ResyncSchedule("@every 1s").ToController()                      // Every second
+// factory.New().ResyncSchedule("@hourly").ToController()                        // Every hour
+// factory.New().ResyncSchedule("30 * * * *").ToController()                     // Every hour on the half hour
+// factory.New().ResyncSchedule("15 30 * * * *").ToController()                  // Every hour on the half hour and 15 seconds
+// factory.New().ResyncSchedule("CRON_TZ=Europe/Prague 0 3 * * *").ToController() // Every day at 3AM in Prague
 //
 // Note: The controller context passed to Sync() function in this case does not contain the object metadata or object itself.
 //
 //	This can be used to detect periodical resyncs, but normal Sync() have to be cautious about `nil` objects.
 func (f *Factory) ResyncSchedule(schedules ...string) *Factory {
-	f.resyncSchedules = append(f.resyncSchedules, schedules...)
+	for _, schedule := range schedules {
+		f.resyncSchedules = append(f.resyncSchedules, resyncSchedule{spec: schedule})
+	}
+	return f
+}
+
+// ResyncScheduleWithQueueKey is like ResyncSchedule, but the given queue key is added to the queue when the schedule fires.
+// This allows a single controller to run different jobs on different schedules:
+//
+// factory.New().ResyncScheduleWithQueueKey("@hourly", "cleanup").ResyncScheduleWithQueueKey("0 3 * * *", "backup").ToController()
+//
+// The Sync() function can also get the schedule name (see ScheduleName) and the planned fire time via framework.ScheduledRunFromContext.
+func (f *Factory) ResyncScheduleWithQueueKey(schedule, queueKey string) *Factory {
+	return f.ResyncScheduleWithOptions(schedule, ScheduleQueueKey(queueKey))
+}
+
+// ResyncScheduleWithQueueKeyFunc is like ResyncSchedule, but the queue key added to the queue when the schedule fires is
+// produced by the queueKeyFn. The function get the schedule name (see ScheduleName) and the planned fire time.
+func (f *Factory) ResyncScheduleWithQueueKeyFunc(schedule string, queueKeyFn framework.ScheduleQueueKeyFunc) *Factory {
+	return f.ResyncScheduleWithOptions(schedule, ScheduleQueueKeyFunc(queueKeyFn))
+}
+
+// ResyncScheduleWithOptions is like ResyncSchedule, but allows to configure the queue key and the missed run policy of the schedule.
+// Example:
+//
+// factory.New().ResyncScheduleWithOptions("0 3 * * *", ScheduleQueueKey("backup"), ScheduleMissedRunPolicy(framework.MissedRunOnce)).ToController()
+func (f *Factory) ResyncScheduleWithOptions(schedule string, options ...ScheduleOption) *Factory {
+	s := resyncSchedule{spec: schedule}
+	for _, option := range options {
+		option(&s)
+	}
+	f.resyncSchedules = append(f.resyncSchedules, s)
+	return f
+}
+
+// ScheduleOption configures the schedule added by ResyncScheduleWithOptions.
+type ScheduleOption func(*resyncSchedule)
+
+// ScheduleName sets the name of the schedule. The name identifies the last run of the schedule in the schedule store, so it
+// should not change when the schedule spec changes. The names must be unique within the controller, the schedules without
+// the name are named by their position ("schedule-0", "schedule-1", ...).
+func ScheduleName(name string) ScheduleOption {
+	return func(s *resyncSchedule) {
+		s.name = name
+	}
+}
+
+// ScheduleQueueKey sets the queue key added to the queue when the schedule fires.
+func ScheduleQueueKey(queueKey string) ScheduleOption {
+	return ScheduleQueueKeyFunc(func(framework.ScheduledRun) string {
+		return queueKey
+	})
+}
+
+// ScheduleQueueKeyFunc sets the function producing the queue key added to the queue when the schedule fires.
+func ScheduleQueueKeyFunc(queueKeyFn framework.ScheduleQueueKeyFunc) ScheduleOption {
+	return func(s *resyncSchedule) {
+		s.queueKeyFn = queueKeyFn
+	}
+}
+
+// ScheduleMissedRunPolicy sets what happens with the runs missed while the process was down or the previous run was not
+// processed yet. The last runs are persisted in the store set by WithScheduleStore, without the store only the runs
+// missed while the controller is running are detected.
+func ScheduleMissedRunPolicy(policy framework.MissedRunPolicy) ScheduleOption {
+	return func(s *resyncSchedule) {
+		s.missedRunPolicy = policy
+	}
+}
+
+// WithScheduleStore sets the store used to persist the last runs of the resync schedules (see the schedulestore package).
+func (f *Factory) WithScheduleStore(store framework.ScheduleStore) *Factory {
+	f.scheduleStore = store
+	return f
+}
+
+// WithClock allows to specify the clock used to trigger the ResyncSchedule runs and the ResyncEvery resyncs.
+// This is useful during unit testing where a fake clock can be used to test the schedules and resyncs deterministically.
+func (f *Factory) WithClock(clock clock.WithTicker) *Factory {
+	f.clock = clock
+	return f
+}
+
+// WithCacheSyncTimeout sets how long the controller waits for the informers to sync when it starts. When the informers
+// are not synced in time, the controller exits the process rather than running without the caches.
+// The timeout must be positive, the default timeout is 10 minutes.
+func (f *Factory) WithCacheSyncTimeout(timeout time.Duration) *Factory {
+	f.cacheSyncTimeout = timeout
+	return f
+}
+
+// WithWorkerAutoscaling enables adjusting the number of controller workers at runtime based on the queue depth and sync latency.
+// The number of workers passed to Run() is the initial number of workers.
+// The number of workers can be also changed manually via framework.WorkerScaler interface implemented by the controller.
+func (f *Factory) WithWorkerAutoscaling(policy framework.WorkerAutoscalingPolicy) *Factory {
+	f.workerAutoscalingPolicy = &policy
 	return f
 }
 
@@ -271,80 +578,319 @@ func (f *Factory) WithSyncDegradedOnError(operatorClient operatorv1helpers.Opera
 }
 */
 
-// Controller produce a runnable controller.
+// ToController produce a runnable controller. It panics when the controller cannot be created (eg. the sync function is
+// not set, a schedule cannot be parsed or the informers cannot be set up), use Build() to handle the configuration errors.
+// The configuration errors ToController() accepted before the configuration was validated (eg. empty name, an informer
+// registered twice or the bare informer registered with event handlers) are logged as warnings, Build() rejects them.
 func (f *Factory) ToController(name string, eventRecorder events.Recorder) framework.Controller {
-	if f.sync == nil {
-		panic(fmt.Errorf("WithSync() must be used before calling ToController() in %q", name))
+	c, err := f.build(name, eventRecorder, true)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Build produce a runnable controller. All options are validated before the controller is created and all configuration
+// errors are returned at once as an aggregate error. The informers are not modified when the configuration is invalid:
+// no event handlers are left registered and no informers are created by the shared informers when an error is returned.
+// The only exception is adding the index by WithIndexedReferences() to an informer that was started meanwhile, which cannot
+// be checked upfront. The indexes of the other WithIndexedReferences() added before such error are left in place, because
+// the informers do not support removing indexes.
+func (f *Factory) Build(name string, eventRecorder events.Recorder) (framework.Controller, error) {
+	return f.build(name, eventRecorder, false)
+}
+
+// build creates the controller, the tolerated configuration errors (see toleratedError) are logged instead of returned
+// when tolerate is true.
+func (f *Factory) build(name string, eventRecorder events.Recorder, tolerate bool) (framework.Controller, error) {
+	var invalid []error
+	for _, err := range f.validate(name) {
+		if _, ok := err.(toleratedError); ok && tolerate {
+			klog.Warningf("Controller %q configuration is not valid and will be rejected by Build(): %v", name, err)
+			continue
+		}
+		invalid = append(invalid, err)
+	}
+	if err := errorutil.NewAggregate(invalid); err != nil {
+		return nil, fmt.Errorf("invalid configuration of controller %q: %w", name, err)
+	}
+	syncFn := f.sync
+	batchSync := f.batchSync
+	if len(f.namespaceSelections) > 0 {
+		selections := append([]namespaceSelection{}, f.namespaceSelections...)
+		syncFn, batchSync = acknowledgingSync(selections, syncFn), acknowledgingBatchSync(selections, batchSync)
+	}
+	if batchSync != nil {
+		syncFn = func(ctx gocontext.Context, controllerContext framework.Context) error {
+			key := controllerContext.QueueKey()
+			return batchSync(ctx, controllerContext, []string{key})[key]
+		}
 	}
 
 	var ctx framework.Context
-	if f.syncContext != nil {
+	switch {
+	case f.syncContext != nil:
 		ctx = f.syncContext
-	} else {
+	case f.newQueue != nil:
+		ctx = context.New(name, eventRecorder, context.WithQueue(f.newQueue(name)))
+	default:
 		ctx = context.New(name, eventRecorder)
 	}
 
-	var cronSchedules []cron.Schedule
+	var schedules []controller.NamedSchedule
 	if len(f.resyncSchedules) > 0 {
 		var errors []error
 		for _, schedule := range f.resyncSchedules {
-			if s, err := cron.ParseStandard(schedule); err != nil {
+			if s, err := ParseSchedule(schedule.spec); err != nil {
 				errors = append(errors, err)
 			} else {
-				cronSchedules = append(cronSchedules, s)
+				schedules = append(schedules, controller.NamedSchedule{Name: schedule.name, Schedule: s, QueueKeyFunc: schedule.queueKeyFn, MissedRunPolicy: schedule.missedRunPolicy})
 			}
 		}
 		if err := errorutil.NewAggregate(errors); err != nil {
-			panic(fmt.Errorf("failed to parse controller schedules for %q: %v", name, err))
+			return nil, fmt.Errorf("failed to parse controller schedules for %q: %w", name, err)
 		}
 	}
 
 	informersToSync := []cache.InformerSynced{}
+	var registrations []controller.HandlerRegistration
+	var errs []error
+	var debouncers []*queue.Debouncer
+	// addEventHandler registers the handler and waits for the handler to receive the initial list rather than for the informer to sync
+	addEventHandler := func(informer framework.Informer, handler cache.ResourceEventHandler) {
+		registration, err := informer.AddEventHandler(handler)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to add event handler to informer %T: %w", informer, err))
+			return
+		}
+		r := controller.HandlerRegistration{Informer: informer, Registration: registration}
+		registrations = append(registrations, r)
+		informersToSync = append(informersToSync, r.HasSynced)
+	}
+	handlerFor := func(informer framework.Informer, queueKeyFn framework.ObjectQueueKeysFunc, predicate framework.Predicate, priority *int) cache.ResourceEventHandler {
+		handler, debouncer := f.eventHandler(name, ctx, informer, queueKeyFn, predicate, priority)
+		if debouncer != nil {
+			debouncers = append(debouncers, debouncer)
+		}
+		return handler
+	}
 
 	for i := range f.informerQueueKeys {
 		for d := range f.informerQueueKeys[i].informers {
 			informer := f.informerQueueKeys[i].informers[d]
 			queueKeyFn := f.informerQueueKeys[i].queueKeyFn
-			if _, err := informer.AddEventHandler(ctx.(context.Context).EventHandler(queueKeyFn, f.informerQueueKeys[i].filter)); err != nil {
-				panic(err)
-			}
-			informersToSync = append(informersToSync, informer.HasSynced)
+			addEventHandler(informer, handlerFor(informer, queueKeyFn, f.informerQueueKeys[i].predicate, f.informerQueueKeys[i].priority))
 		}
 	}
 
 	for i := range f.informers {
 		for d := range f.informers[i].informers {
 			informer := f.informers[i].informers[d]
-			if _, err := informer.AddEventHandler(ctx.(context.Context).EventHandler(DefaultQueueKeysFunc, f.informers[i].filter)); err != nil {
-				panic(err)
-			}
-			informersToSync = append(informersToSync, informer.HasSynced)
+			addEventHandler(informer, handlerFor(informer, DefaultQueueKeysFunc, f.informers[i].predicate, nil))
 		}
 	}
 
+	// the referenced informers use the indexer of the primary informer, the index is added once all event handlers are registered
+	for i := range f.indexedReferences {
+		indexer := f.indexedReferences[i].primary.(indexedInformer).GetIndexer()
+		queueKeyFn := IndexQueueKeysFunc(indexer, f.indexedReferences[i].index.name)
+		for _, informer := range f.indexedReferences[i].referenced {
+			addEventHandler(informer, handlerFor(informer, queueKeyFn, nil, nil))
+		}
+		informersToSync = append(informersToSync, f.indexedReferences[i].primary.HasSynced)
+	}
+
+	namespaceQueue := ctx.Queue()
+	enqueueNamespace := func(name string) {
+		namespaceQueue.Add(name)
+	}
+	for _, s := range f.namespaceSelections {
+		addEventHandler(s.informer, s.selection.eventHandler(enqueueNamespace))
+	}
+
 	for i := range f.bareInformers {
 		informersToSync = append(informersToSync, f.bareInformers[i].HasSynced)
 	}
 
 	for i := range f.namespaceInformers {
-		if _, err := f.namespaceInformers[i].informer.AddEventHandler(ctx.(context.Context).EventHandler(DefaultQueueKeysFunc, f.namespaceInformers[i].nsFilter)); err != nil {
-			panic(err)
+		informer := f.namespaceInformers[i].informer
+		addEventHandler(informer, handlerFor(informer, DefaultQueueKeysFunc, f.namespaceInformers[i].predicate, nil))
+	}
+
+	// the changes that cannot be reverted (indexes and informers created by the shared informers) are made only when
+	// all event handlers were registered
+	if len(errs) == 0 {
+		for i := range f.indexedReferences {
+			if _, err := f.indexedReferences[i].addIndex(); err != nil {
+				errs = append(errs, fmt.Errorf("invalid indexed references: %w", err))
+			}
+		}
+	}
+
+	var resourceSources []resyncSource
+	for i := 0; len(errs) == 0 && i < len(f.resourceInformers); i++ {
+		resource := f.resourceInformers[i]
+		genericInformer, err := f.sharedInformers.ForResource(resource)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to create informer: %w", err))
+			continue
+		}
+		queueKeyFn := resource.QueueKeysFunc
+		if queueKeyFn == nil {
+			queueKeyFn = DefaultQueueKeysFunc
+		}
+		informer := genericInformer.Informer()
+		addEventHandler(informer, handlerFor(informer, queueKeyFn, resource.Predicate, nil))
+		resourceSources = append(resourceSources, resyncSource{informer: informer, predicate: resource.Predicate, queueKeyFn: queueKeyFn})
+		ctx = ctx.(context.Context).WithLister(resource.Resource, genericInformer.Lister())
+	}
+
+	if err := errorutil.NewAggregate(errs); err != nil {
+		if removeErr := controller.RemoveEventHandlers(registrations...); removeErr != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to remove event handlers of controller %q: %v", name, removeErr))
+		}
+		for _, debouncer := range debouncers {
+			debouncer.ShutDown()
+		}
+		return nil, fmt.Errorf("unable to create controller %q: %w", name, err)
+	}
+
+	for _, s := range f.namespaceSelections {
+		var store cache.Store
+		if informer, ok := s.informer.(storeInformer); ok {
+			store = informer.GetStore()
 		}
-		informersToSync = append(informersToSync, f.namespaceInformers[i].informer.HasSynced)
+		s.selection.register(name, store, enqueueNamespace)
 	}
 
 	f.cachesToSync = append(f.cachesToSync, informersToSync...)
 
+	options := []controller.Option{controller.WithSchedules(schedules...), controller.WithHandlerRegistrations(registrations...), controller.WithDebouncers(debouncers...)}
+	if f.clock != nil {
+		options = append(options, controller.WithClock(f.clock))
+	}
+	if f.scheduleStore != nil {
+		options = append(options, controller.WithScheduleStore(f.scheduleStore))
+	}
+	if len(f.resourceInformers) > 0 {
+		options = append(options, controller.WithInformerStarters(f.sharedInformers.Start))
+	}
+	if f.batchSync != nil {
+		options = append(options, controller.WithBatchSync(batchSync, f.batchMaxSize, f.batchMaxLatency))
+	}
+	if f.workerAutoscalingPolicy != nil {
+		options = append(options, controller.WithWorkerAutoscaling(*f.workerAutoscalingPolicy))
+	}
+	if f.resyncOptions.jitterFactor > 0 {
+		options = append(options, controller.WithResyncJitter(f.resyncOptions.jitterFactor))
+	}
+	if f.resyncOptions.initialDelay > 0 {
+		options = append(options, controller.WithResyncInitialDelay(f.resyncOptions.initialDelay))
+	}
+	if f.resyncOptions.fullResync {
+		options = append(options, controller.WithResyncKeysFunc(f.resyncKeysFunc(resourceSources...)))
+	}
+	if f.resyncOptions.spread {
+		options = append(options, controller.WithResyncSpread())
+	}
+
 	c := controller.New(
 		name,
-		f.sync,
+		syncFn,
 		ctx,
 		f.resyncInterval,
-		cronSchedules,
+		nil,
 		f.postStartHooks,
 		append([]cache.InformerSynced{}, f.cachesToSync...),
-		defaultCacheSyncTimeout,
+		f.cacheSyncTimeout,
+		options...,
 	)
 
-	return c
+	return c, nil
+}
+
+// eventHandler returns the event handler for the informer, the keys are debounced when WithInformerDebounce was used for the informer.
+// The debouncer used by the handler is returned as well (nil when the keys are not debounced), so it can be shut down with the controller.
+func (f *Factory) eventHandler(name string, ctx framework.Context, informer framework.Informer, queueKeyFn framework.ObjectQueueKeysFunc, predicate framework.Predicate, priority *int) (cache.ResourceEventHandler, *queue.Debouncer) {
+	syncContext := ctx.(context.Context)
+	var options []context.EventHandlerOption
+	if priority != nil {
+		options = append(options, context.WithEnqueuePriority(*priority))
+	}
+	var debouncer *queue.Debouncer
+	for _, debounced := range f.debouncedInformers {
+		for _, debouncedInformer := range debounced.informers {
+			if debouncedInformer != informer {
+				continue
+			}
+			config := queue.DebounceConfig{Name: name, Window: debounced.window, MaxWait: debounced.maxWait, Priority: priority}
+			if f.clock != nil {
+				config.Clock = f.clock
+			}
+			// the last debounce configuration of the informer is used
+			debouncer = queue.NewDebouncer(syncContext.Queue(), config)
+		}
+	}
+	if debouncer != nil {
+		options = append(options, context.WithDebouncer(debouncer))
+	}
+	return syncContext.PredicateEventHandler(queueKeyFn, predicate, options...), debouncer
+}
+
+// storeInformer is implemented by informers that provide access to their store (eg. SharedIndexInformer).
+type storeInformer interface {
+	GetStore() cache.Store
+}
+
+type resyncSource struct {
+	informer   framework.Informer
+	predicate  framework.Predicate
+	queueKeyFn framework.ObjectQueueKeysFunc
+}
+
+// resyncKeysFunc returns function that lists all objects in registered informers stores and return their unique queue keys.
+func (f *Factory) resyncKeysFunc(extraSources ...resyncSource) func() []string {
+	sources := append([]resyncSource{}, extraSources...)
+	for i := range f.informerQueueKeys {
+		for _, informer := range f.informerQueueKeys[i].informers {
+			sources = append(sources, resyncSource{informer: informer, predicate: f.informerQueueKeys[i].predicate, queueKeyFn: f.informerQueueKeys[i].queueKeyFn})
+		}
+	}
+	for i := range f.informers {
+		for _, informer := range f.informers[i].informers {
+			sources = append(sources, resyncSource{informer: informer, predicate: f.informers[i].predicate, queueKeyFn: DefaultQueueKeysFunc})
+		}
+	}
+	for i := range f.namespaceInformers {
+		sources = append(sources, resyncSource{informer: f.namespaceInformers[i].informer, predicate: f.namespaceInformers[i].predicate, queueKeyFn: DefaultQueueKeysFunc})
+	}
+
+	return func() []string {
+		seen := sets.New[string]()
+		var keys []string
+		for _, source := range sources {
+			informer, ok := source.informer.(storeInformer)
+			if !ok {
+				continue
+			}
+			for _, obj := range informer.GetStore().List() {
+				if source.predicate != nil && !source.predicate.Create(obj) {
+					continue
+				}
+				runtimeObj, ok := obj.(runtime.Object)
+				if !ok {
+					continue
+				}
+				for _, key := range source.queueKeyFn(runtimeObj) {
+					if seen.Has(key) {
+						continue
+					}
+					seen.Insert(key)
+					keys = append(keys, key)
+				}
+			}
+		}
+		sort.Strings(keys)
+		return keys
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/mfojtik/controller-framework/pkg/framework"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -15,8 +16,14 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	clocktesting "k8s.io/utils/clock/testing"
 
+	context2 "github.com/mfojtik/controller-framework/pkg/context"
 	"github.com/mfojtik/controller-framework/pkg/events"
+	"github.com/mfojtik/controller-framework/pkg/predicates"
+	"github.com/mfojtik/controller-framework/pkg/queue"
+	"github.com/mfojtik/controller-framework/pkg/schedulestore"
 )
 
 /*
@@ -312,3 +319,724 @@ func TestControllerWithQueueFunction(t *testing.T) {
 		t.Fatal("test timeout")
 	}
 }
+
+func TestControllerWithPredicates(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	secretInformers := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithNamespace("test"))
+	clusterInformers := informers.NewSharedInformerFactory(kubeClient, 0)
+	secretInformer := secretInformers.Core().V1().Secrets().Informer()
+	namespaceInformer := clusterInformers.Core().V1().Namespaces().Informer()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	secretInformers.Start(ctx.Done())
+	clusterInformers.Start(ctx.Done())
+
+	synced := make(chan string, 10)
+	controller := New().
+		WithPredicatedInformersQueueKeysFunc(func(obj runtime.Object) []string {
+			metaObj, _ := apimeta.Accessor(obj)
+			return []string{metaObj.GetNamespace() + "/" + metaObj.GetName()}
+		}, predicates.GenerationChanged(), secretInformer).
+		WithPredicatedNamespaceInformer(namespaceInformer, predicates.LabelsChanged(), "interesting").
+		WithSync(func(ctx context.Context, syncContext framework.Context) error {
+			synced <- syncContext.QueueKey()
+			return nil
+		}).ToController("FakeController", events.NewInMemoryRecorder("fake-controller"))
+	go controller.Run(ctx, 1)
+
+	expectSyncs := func(keys ...string) {
+		t.Helper()
+		for _, key := range keys {
+			select {
+			case got := <-synced:
+				if got != key {
+					t.Errorf("expected sync of %q, got %q", key, got)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatalf("expected sync of %q", key)
+			}
+		}
+		select {
+		case got := <-synced:
+			t.Errorf("unexpected sync of %q", got)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	secret := &v1.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "test", Name: "a", Generation: 1}}
+	if _, err := kubeClient.CoreV1().Secrets("test").Create(ctx, secret, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectSyncs("test/a")
+	for _, name := range []string{"interesting", "other"} {
+		if _, err := kubeClient.CoreV1().Namespaces().Create(ctx, &v1.Namespace{ObjectMeta: meta.ObjectMeta{Name: name}}, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectSyncs(framework.DefaultQueueKey)
+
+	// the updates not accepted by the predicates do not trigger the sync
+	secret.Labels = map[string]string{"changed": "true"}
+	if _, err := kubeClient.CoreV1().Secrets("test").Update(ctx, secret, meta.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, &v1.Namespace{ObjectMeta: meta.ObjectMeta{Name: "interesting", Annotations: map[string]string{"changed": "true"}}}, meta.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, &v1.Namespace{ObjectMeta: meta.ObjectMeta{Name: "other", Labels: map[string]string{"changed": "true"}}}, meta.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectSyncs()
+
+	secret.Generation = 2
+	if _, err := kubeClient.CoreV1().Secrets("test").Update(ctx, secret, meta.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectSyncs("test/a")
+	if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, &v1.Namespace{ObjectMeta: meta.ObjectMeta{Name: "interesting", Labels: map[string]string{"changed": "true"}}}, meta.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectSyncs(framework.DefaultQueueKey)
+}
+
+// recordingInformer records the event handlers registered by the controller and how the controller uses their registrations.
+type recordingInformer struct {
+	cache.SharedIndexInformer
+
+	lock           sync.Mutex
+	handled        int
+	hasSyncedCalls int
+	removed        int
+}
+
+type recordingRegistration struct {
+	cache.ResourceEventHandlerRegistration
+	informer *recordingInformer
+}
+
+func (r *recordingRegistration) HasSynced() bool {
+	r.informer.lock.Lock()
+	r.informer.hasSyncedCalls++
+	r.informer.lock.Unlock()
+	return r.ResourceEventHandlerRegistration.HasSynced()
+}
+
+func (i *recordingInformer) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
+	registration, err := i.SharedIndexInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			i.lock.Lock()
+			i.handled++
+			i.lock.Unlock()
+			handler.OnAdd(obj, false)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &recordingRegistration{ResourceEventHandlerRegistration: registration, informer: i}, nil
+}
+
+func (i *recordingInformer) RemoveEventHandler(handle cache.ResourceEventHandlerRegistration) error {
+	i.lock.Lock()
+	i.removed++
+	i.lock.Unlock()
+	return i.SharedIndexInformer.RemoveEventHandler(handle.(*recordingRegistration).ResourceEventHandlerRegistration)
+}
+
+func TestControllerRemovesEventHandlers(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	kubeInformers := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithNamespace("test"))
+	informer := &recordingInformer{SharedIndexInformer: kubeInformers.Core().V1().Secrets().Informer()}
+
+	informersCtx, informersCancel := context.WithCancel(context.TODO())
+	defer informersCancel()
+	kubeInformers.Start(informersCtx.Done())
+
+	synced := make(chan string, 10)
+	controller := New().WithInformersQueueKeysFunc(func(obj runtime.Object) []string {
+		metaObj, _ := apimeta.Accessor(obj)
+		return []string{metaObj.GetName()}
+	}, informer).WithSync(func(ctx context.Context, syncContext framework.Context) error {
+		synced <- syncContext.QueueKey()
+		return nil
+	}).ToController("FakeController", events.NewInMemoryRecorder("fake-controller"))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		controller.Run(ctx, 1)
+	}()
+
+	if _, err := kubeClient.CoreV1().Secrets("test").Create(ctx, &v1.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "test", Name: "a"}}, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-synced:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected sync")
+	}
+	cancel()
+	<-done
+
+	informer.lock.Lock()
+	hasSyncedCalls, removed, handled := informer.hasSyncedCalls, informer.removed, informer.handled
+	informer.lock.Unlock()
+	if hasSyncedCalls == 0 {
+		t.Errorf("expected the controller to wait for the handler registration to sync")
+	}
+	if removed != 1 {
+		t.Errorf("expected the event handler to be removed, removed %d", removed)
+	}
+
+	// the informer keeps running, but the removed handler does not receive the events
+	if _, err := kubeClient.CoreV1().Secrets("test").Create(context.TODO(), &v1.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "test", Name: "b"}}, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return len(informer.GetStore().ListKeys()) == 2, nil
+	}); err != nil {
+		t.Fatal("expected the informer to observe the second secret")
+	}
+	informer.lock.Lock()
+	defer informer.lock.Unlock()
+	if informer.handled != handled {
+		t.Errorf("expected the removed handler to not receive events, handled %d events after removal", informer.handled-handled)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		schedule    string
+		expectNext  time.Time
+		expectError string
+	}{
+		{schedule: "@hourly", expectNext: time.Date(2023, 7, 1, 1, 0, 0, 0, time.UTC)},
+		{schedule: "@every 90s", expectNext: from.Add(90 * time.Second)},
+		{schedule: "30 * * * *", expectNext: time.Date(2023, 7, 1, 0, 30, 0, 0, time.UTC)},
+		{schedule: "15 30 * * * *", expectNext: time.Date(2023, 7, 1, 0, 30, 15, 0, time.UTC)},
+		{schedule: "CRON_TZ=Europe/Prague 0 3 * * *", expectNext: time.Date(2023, 7, 1, 3, 0, 0, 0, prague)},
+		{schedule: "TZ=Europe/Prague 10 0 3 * * *", expectNext: time.Date(2023, 7, 1, 3, 0, 10, 0, prague)},
+		{schedule: "CRON_TZ=Mars/Olympus 0 3 * * *", expectError: `unknown time zone "Mars/Olympus"`},
+		{schedule: "CRON_TZ=Europe/Prague", expectError: "missing schedule"},
+		{schedule: "* * *", expectError: "expected 5 or 6 (with seconds) fields, found 3"},
+		{schedule: "61 * * * *", expectError: "invalid schedule"},
+	}
+	for _, test := range tests {
+		t.Run(test.schedule, func(t *testing.T) {
+			s, err := ParseSchedule(test.schedule)
+			if len(test.expectError) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := s.Next(from); !next.Equal(test.expectNext) {
+				t.Errorf("expected next run at %s, got %s", test.expectNext, next)
+			}
+		})
+	}
+}
+
+func TestControllerScheduledWithFakeClock(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	fakeClock := clocktesting.NewFakeClock(time.Date(2023, 7, 1, 2, 59, 0, 0, prague))
+	syncCalled := make(chan time.Time, 10)
+	controller := New().ResyncSchedule("CRON_TZ=Europe/Prague 0 3 * * *").WithClock(fakeClock).WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		syncCalled <- fakeClock.Now()
+		return nil
+	}).ToController("test", events.NewInMemoryRecorder("fake-controller"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go controller.Run(ctx, 1)
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatalf("expected the schedule to wait for the clock: %v", err)
+	}
+	select {
+	case <-syncCalled:
+		t.Fatal("expected no sync before the scheduled time")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fakeClock.Step(time.Minute)
+	select {
+	case firedAt := <-syncCalled:
+		if !firedAt.Equal(time.Date(2023, 7, 1, 3, 0, 0, 0, prague)) {
+			t.Errorf("expected sync at 3AM in Prague, got %s", firedAt)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected sync after the scheduled time")
+	}
+}
+
+func TestToControllerInvalidSchedule(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected panic")
+		}
+		if !strings.Contains(fmt.Sprint(r), `unknown time zone "Nowhere"`) {
+			t.Errorf("expected clear error, got %v", r)
+		}
+	}()
+	New().ResyncSchedule("CRON_TZ=Nowhere 0 3 * * *").WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}).ToController("test", events.NewInMemoryRecorder("fake-controller"))
+}
+
+func TestResyncInitialDelayAndJitter(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	recorder := events.NewInMemoryRecorder("fake-controller")
+	syncCalled := make(chan struct{}, 10)
+	controller := New().ResyncEvery(10*time.Second, ResyncJitter(1.0), ResyncInitialDelay(time.Minute)).WithClock(fakeClock).WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		syncCalled <- struct{}{}
+		return nil
+	}).ToController("test", recorder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go controller.Run(ctx, 1)
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatalf("expected the resync to wait for the initial delay: %v", err)
+	}
+	select {
+	case <-syncCalled:
+		t.Fatal("expected no resync before the initial delay")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fakeClock.Step(time.Minute)
+	select {
+	case <-syncCalled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected resync after the initial delay")
+	}
+
+	found := false
+	for _, e := range recorder.Events() {
+		if e.Reason == "FastControllerResync" {
+			found = true
+			if !strings.Contains(e.Message, "15s on average with jitter factor 1.00") {
+				t.Errorf("expected the warning to mention the effective interval, got %q", e.Message)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected FastControllerResync warning")
+	}
+}
+
+func TestControllerScheduledWithQueueKeys(t *testing.T) {
+	start := time.Date(2023, 7, 1, 2, 59, 0, 0, time.UTC)
+	fakeClock := clocktesting.NewFakeClock(start)
+	syncCalled := make(chan framework.ScheduledRun, 10)
+	syncKeys := make(chan string, 10)
+	controller := New().
+		ResyncScheduleWithQueueKey("CRON_TZ=UTC 0 * * * *", "hourly").
+		ResyncScheduleWithQueueKeyFunc("CRON_TZ=UTC 0 3 * * *", func(run framework.ScheduledRun) string {
+			return "backup-" + run.PlannedTime.Format("2006-01-02")
+		}).
+		WithClock(fakeClock).
+		WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+			run, ok := framework.ScheduledRunFromContext(ctx)
+			if !ok {
+				t.Errorf("expected scheduled run in context for key %q", controllerContext.QueueKey())
+			}
+			syncKeys <- controllerContext.QueueKey()
+			syncCalled <- run
+			return nil
+		}).ToController("test", events.NewInMemoryRecorder("fake-controller"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go controller.Run(ctx, 1)
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatalf("expected the schedules to wait for the clock: %v", err)
+	}
+	fakeClock.Step(time.Minute)
+
+	expected := map[string]string{
+		"hourly":            "schedule-0",
+		"backup-2023-07-01": "schedule-1",
+	}
+	for i := 0; i < len(expected); i++ {
+		select {
+		case key := <-syncKeys:
+			run := <-syncCalled
+			if run.Name != expected[key] {
+				t.Errorf("expected schedule %q for key %q, got %q", expected[key], key, run.Name)
+			}
+			if !run.PlannedTime.Equal(start.Add(time.Minute)) {
+				t.Errorf("expected planned time %s, got %s", start.Add(time.Minute), run.PlannedTime)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("expected sync for both schedules")
+		}
+	}
+}
+
+func TestFullResyncKeys(t *testing.T) {
+	kubeInformers := informers.NewSharedInformerFactoryWithOptions(fake.NewSimpleClientset(), 0, informers.WithNamespace("test"))
+	secretInformer := kubeInformers.Core().V1().Secrets().Informer()
+	configMapInformer := kubeInformers.Core().V1().ConfigMaps().Informer()
+	for _, name := range []string{"b", "a", "ignored"} {
+		if err := secretInformer.GetStore().Add(&v1.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "test", Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := configMapInformer.GetStore().Add(&v1.ConfigMap{ObjectMeta: meta.ObjectMeta{Namespace: "test", Name: "config"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	f := New().
+		ResyncEvery(time.Hour, FullResync()).
+		WithFilteredEventsInformersQueueKeysFunc(func(obj runtime.Object) []string {
+			metaObj, _ := apimeta.Accessor(obj)
+			return []string{metaObj.GetNamespace() + "/" + metaObj.GetName(), "all-secrets"}
+		}, func(obj interface{}) bool { // the filter is accepted as a plain function literal
+			metaObj, _ := apimeta.Accessor(obj)
+			return metaObj.GetName() != "ignored"
+		}, secretInformer).
+		WithInformers(configMapInformer)
+
+	keys := f.resyncKeysFunc()()
+	if expected := "all-secrets,key,test/a,test/b"; strings.Join(keys, ",") != expected {
+		t.Errorf("expected full resync keys %q, got %q", expected, strings.Join(keys, ","))
+	}
+}
+
+func TestControllerScheduleMissedRuns(t *testing.T) {
+	now := time.Date(2023, 7, 1, 3, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		policy     framework.MissedRunPolicy
+		expectRuns []time.Time
+	}{
+		{
+			name:   "skip",
+			policy: framework.MissedRunSkip,
+		},
+		{
+			name:   "run once",
+			policy: framework.MissedRunOnce,
+			// the latest of the missed runs is reported
+			expectRuns: []time.Time{now.Add(-30 * time.Minute)},
+		},
+		{
+			name:       "run all",
+			policy:     framework.MissedRunAll,
+			expectRuns: []time.Time{now.Add(-150 * time.Minute), now.Add(-90 * time.Minute), now.Add(-30 * time.Minute)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := schedulestore.NewInMemoryStore()
+			if err := store.SetLastRun(context.TODO(), "test", "hourly", now.Add(-210*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			fakeClock := clocktesting.NewFakeClock(now)
+			runs := make(chan framework.ScheduledRun, 10)
+			c := New().
+				ResyncScheduleWithOptions("CRON_TZ=UTC 0 * * * *", ScheduleName("hourly"), ScheduleMissedRunPolicy(test.policy)).
+				WithScheduleStore(store).
+				WithClock(fakeClock).
+				WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+					run, _ := framework.ScheduledRunFromContext(ctx)
+					runs <- run
+					return nil
+				}).ToController("test", events.NewInMemoryRecorder("fake-controller"))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go c.Run(ctx, 1)
+
+			for _, expected := range test.expectRuns {
+				select {
+				case run := <-runs:
+					if !run.CatchUp || !run.PlannedTime.Equal(expected) {
+						t.Errorf("expected catch up run planned at %s, got %#v", expected, run)
+					}
+				case <-time.After(10 * time.Second):
+					t.Fatalf("expected catch up run planned at %s", expected)
+				}
+			}
+
+			if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+				return fakeClock.HasWaiters(), nil
+			}); err != nil {
+				t.Fatalf("expected the schedule to wait for the next run: %v", err)
+			}
+			select {
+			case run := <-runs:
+				t.Fatalf("unexpected run %#v", run)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			statuses := c.(framework.ScheduleInspector).Schedules()
+			if len(statuses) != 1 || !statuses[0].NextRun.Equal(now.Add(30*time.Minute)) || statuses[0].MissedRunPolicy != test.policy {
+				t.Errorf("unexpected schedule status: %#v", statuses)
+			}
+
+			fakeClock.Step(30 * time.Minute)
+			select {
+			case run := <-runs:
+				if run.CatchUp || !run.PlannedTime.Equal(now.Add(30*time.Minute)) {
+					t.Errorf("expected run planned at %s, got %#v", now.Add(30*time.Minute), run)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("expected scheduled run")
+			}
+			// the run is recorded after the sync succeeds
+			if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+				lastRun, err := store.LastRun(context.TODO(), "test", "hourly")
+				return lastRun.Equal(now.Add(30 * time.Minute)), err
+			}); err != nil {
+				t.Errorf("expected the last run to be recorded: %v", err)
+			}
+		})
+	}
+}
+
+func TestControllerBatchSync(t *testing.T) {
+	var lock sync.Mutex
+	var batches [][]string
+	attempts := map[string]int{}
+	syncContext := context2.New("test", events.NewInMemoryRecorder("fake-controller"))
+	c := New().WithSyncContext(syncContext).WithBatchSync(func(ctx context.Context, controllerContext framework.Context, keys []string) map[string]error {
+		lock.Lock()
+		defer lock.Unlock()
+		batches = append(batches, keys)
+		errs := map[string]error{}
+		for _, key := range keys {
+			attempts[key]++
+			if key == "b" && attempts[key] == 1 {
+				errs[key] = fmt.Errorf("failed to sync %q", key)
+			}
+		}
+		return errs
+	}, 3, 100*time.Millisecond).ToController("test", events.NewInMemoryRecorder("fake-controller"))
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		syncContext.Queue().Add(key)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 1)
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return attempts["b"] == 2, nil
+	}); err != nil {
+		t.Fatalf("expected failed key to be retried, got batches %v", batches)
+	}
+
+	if err := c.Sync(ctx, syncContext.WithQueueKey("b")); err != nil {
+		t.Errorf("expected Sync() to call batch sync with single key, got %v", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	// the retried key can join the second batch when it is requeued before the batch latency expires
+	if strings.Join(batches[0], ",") != "a,b,c" || !strings.HasPrefix(strings.Join(batches[1], ","), "d,e") || strings.Join(batches[len(batches)-1], ",") != "b" {
+		t.Errorf("unexpected batches: %v", batches)
+	}
+	for _, key := range []string{"a", "c", "d", "e"} {
+		if attempts[key] != 1 {
+			t.Errorf("expected key %q to be synced once, got %d", key, attempts[key])
+		}
+	}
+	if requeues := syncContext.Queue().NumRequeues("a"); requeues != 0 {
+		t.Errorf("expected successful key to be forgotten, got %d requeues", requeues)
+	}
+}
+
+func TestBuildValidation(t *testing.T) {
+	syncFn := func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}
+	batchSyncFn := func(ctx context.Context, controllerContext framework.Context, keys []string) map[string]error {
+		return nil
+	}
+	informer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Core().V1().Secrets().Informer()
+	tests := []struct {
+		name       string
+		factory    *Factory
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:    "valid",
+			factory: New().WithSync(syncFn).WithInformers(informer).ResyncEvery(time.Minute, ResyncJitter(0.1)),
+		},
+		{
+			name:     "missing sync",
+			factory:  New(),
+			expected: []string{"WithSync() or WithBatchSync() must be used"},
+		},
+		{
+			name:     "conflicting sync",
+			factory:  New().WithSync(syncFn).WithBatchSync(batchSyncFn, 10, time.Second),
+			expected: []string{"WithSync() and WithBatchSync() are mutually exclusive"},
+		},
+		{
+			name:     "zero batch latency",
+			factory:  New().WithBatchSync(batchSyncFn, 10, 0),
+			expected: []string{"WithBatchSync() max latency must be positive"},
+		},
+		{
+			name:    "all errors are aggregated",
+			factory: New().WithSync(syncFn).WithInformers(informer, nil, informer).ResyncSchedule("@never", "CRON_TZ=Nowhere 0 3 * * *"),
+			expected: []string{
+				"WithInformers() informer must not be nil",
+				"WithInformers() informer *cache.sharedIndexInformer registered more than once",
+				`invalid schedule "@never"`,
+				`unknown time zone "Nowhere"`,
+			},
+			unexpected: []string{`invalid schedule "@never": invalid schedule`},
+		},
+		{
+			name:     "conflicting queues",
+			factory:  New().WithSync(syncFn).WithPriorityQueue(queue.PriorityQueueConfig{}).WithFairQueue(queue.FairQueueConfig{}),
+			expected: []string{"WithFairQueue() and WithPriorityQueue() are mutually exclusive"},
+		},
+		{
+			name:     "bare informer with handlers",
+			factory:  New().WithSync(syncFn).WithInformers(informer).WithBareInformers(informer),
+			expected: []string{"must not be registered with event handlers"},
+		},
+		{
+			name:     "debounced informer without handlers",
+			factory:  New().WithSync(syncFn).WithInformerDebounce(0, 0, informer),
+			expected: []string{"WithInformerDebounce() window must be positive", "must be registered via WithInformers*() methods"},
+		},
+		{
+			name:     "duplicate schedule names",
+			factory:  New().WithSync(syncFn).ResyncScheduleWithOptions("@hourly", ScheduleName("schedule-1")).ResyncSchedule("@hourly"),
+			expected: []string{`schedule name "schedule-1" is used by more than one schedule`},
+		},
+		{
+			name:     "resource informers without shared informers",
+			factory:  New().WithSync(syncFn).WithResourceInformers(InformerResource{Resource: v1.SchemeGroupVersion.WithResource("secrets")}),
+			expected: []string{"WithSharedInformers() must be used with WithResourceInformers()"},
+		},
+		{
+			name:     "resource informers without dynamic client",
+			factory:  New().WithSync(syncFn).WithSharedInformers(NewSharedInformers(nil, nil, 0)).WithResourceInformers(InformerResource{Resource: v1.SchemeGroupVersion.WithResource("secrets")}),
+			expected: []string{"WithResourceInformers() dynamic client is required for informer of /v1, Resource=secrets"},
+		},
+		{
+			name:     "zero cache sync timeout",
+			factory:  New().WithSync(syncFn).WithCacheSyncTimeout(0),
+			expected: []string{"WithCacheSyncTimeout() timeout must be positive, got 0s"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := test.factory.Build("test", events.NewInMemoryRecorder("test"))
+			if len(test.expected) == 0 {
+				if err != nil || c == nil {
+					t.Fatalf("expected controller, got error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected error to contain %q, got: %v", expected, err)
+				}
+			}
+			for _, unexpected := range test.unexpected {
+				if strings.Contains(err.Error(), unexpected) {
+					t.Errorf("expected error not to contain %q, got: %v", unexpected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestToControllerToleratedConfiguration(t *testing.T) {
+	informer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Core().V1().Secrets().Informer()
+	factory := New().WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}).WithInformers(informer, informer).WithBareInformers(informer).ResyncEvery(-time.Minute)
+
+	// the configuration accepted by ToController() before the validation is still accepted
+	if c := factory.ToController("", events.NewInMemoryRecorder("test")); c == nil {
+		t.Fatal("expected controller")
+	}
+	_, err := factory.Build("", events.NewInMemoryRecorder("test"))
+	for _, expected := range []string{
+		"controller name must not be empty",
+		"WithInformers() informer *cache.sharedIndexInformer registered more than once",
+		"must not be registered with event handlers",
+		"ResyncEvery() interval must not be negative",
+	} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected Build() error to contain %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestBuildStoppedInformer(t *testing.T) {
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	informer := informerFactory.Core().V1().Secrets().Informer()
+	stopCh := make(chan struct{})
+	informerFactory.Start(stopCh)
+	close(stopCh)
+	informerFactory.Shutdown()
+
+	_, err := New().WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}).WithInformers(informer).Build("test", events.NewInMemoryRecorder("test"))
+	if err == nil || !strings.Contains(err.Error(), "unable to add event handler") {
+		t.Errorf("expected error adding event handler to stopped informer, got: %v", err)
+	}
+}
+
+func TestBuildErrorDoesNotAddIndex(t *testing.T) {
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	stopped := informerFactory.Core().V1().ConfigMaps().Informer()
+	stopCh := make(chan struct{})
+	informerFactory.Start(stopCh)
+	close(stopCh)
+	informerFactory.Shutdown()
+
+	primary := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Core().V1().Secrets().Informer()
+	_, err := New().WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}).WithIndexedReferences(primary, NewIndex("byConfigMap", func(obj interface{}) ([]string, error) {
+		return nil, nil
+	}), stopped).Build("test", events.NewInMemoryRecorder("test"))
+	if err == nil || !strings.Contains(err.Error(), "unable to add event handler") {
+		t.Fatalf("expected error adding event handler to stopped informer, got: %v", err)
+	}
+	if _, exists := primary.GetIndexer().GetIndexers()["byConfigMap"]; exists {
+		t.Errorf("expected the index not to be added when Build() fails")
+	}
+}
@@ -0,0 +1,132 @@
+package factory
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// indexedInformer is implemented by informers that support indexing (eg. SharedIndexInformer).
+type indexedInformer interface {
+	AddIndexers(indexers cache.Indexers) error
+	GetIndexer() cache.Indexer
+}
+
+type indexedReferences struct {
+	primary    framework.Informer
+	index      *Index
+	referenced []framework.Informer
+}
+
+// Index is the named index of the primary informer used by WithIndexedReferences. The controllers sharing the informer
+// share the index by passing the same *Index, the index name cannot be registered with another *Index or by other code.
+type Index struct {
+	name      string
+	indexFunc cache.IndexFunc
+}
+
+// NewIndex returns the index with the given name. The indexFunc must return the "namespace/name" keys of the objects
+// referenced by the indexed object.
+func NewIndex(name string, indexFunc cache.IndexFunc) *Index {
+	return &Index{name: name, indexFunc: indexFunc}
+}
+
+// Name returns the name of the index, use it to look up the objects in the indexer (eg. with IndexQueueKeysFunc).
+func (i *Index) Name() string {
+	return i.name
+}
+
+// registeredIndexes records the *Index registered under each index name of the indexers, so the controllers sharing the
+// informer can tell their index from a different index registered with the same name.
+var registeredIndexes = struct {
+	lock    sync.Mutex
+	indexes map[cache.Indexer]map[string]*Index
+}{indexes: map[cache.Indexer]map[string]*Index{}}
+
+// WithIndexedReferences is used to sync the primary objects when the objects they reference change (eg. a shared Secret
+// referenced by name from many custom resources).
+// The index is registered in the primary informer. When an object observed by the referenced informers changes, all primary
+// objects referencing it are looked up using the index and their "namespace/name" keys are added to the queue.
+// The primary informer must support indexing and must not be started before Build() is called, otherwise Build() returns an error.
+// When the primary informer already has the index (eg. registered by another controller), the existing index is used.
+// Build() returns an error when the index name is registered in the primary informer with a different *Index.
+func (f *Factory) WithIndexedReferences(primary framework.Informer, index *Index, referenced ...framework.Informer) *Factory {
+	f.indexedReferences = append(f.indexedReferences, indexedReferences{
+		primary:    primary,
+		index:      index,
+		referenced: referenced,
+	})
+	return f
+}
+
+// IndexQueueKeysFunc returns queue keys function that maps the object to the "namespace/name" keys of the objects found in
+// the indexer using the object "namespace/name" key as the index value.
+func IndexQueueKeysFunc(indexer cache.Indexer, indexName string) framework.ObjectQueueKeysFunc {
+	return func(obj runtime.Object) []string {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return nil
+		}
+		referencing, err := indexer.ByIndex(indexName, key)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to get objects referencing %q: %v", key, err))
+			return nil
+		}
+		keys := make([]string, 0, len(referencing))
+		for _, o := range referencing {
+			referencingKey, err := cache.MetaNamespaceKeyFunc(o)
+			if err != nil {
+				utilruntime.HandleError(err)
+				continue
+			}
+			keys = append(keys, referencingKey)
+		}
+		return keys
+	}
+}
+
+// addIndex registers the index on the primary informer and returns its indexer.
+func (r indexedReferences) addIndex() (cache.Indexer, error) {
+	informer, ok := r.primary.(indexedInformer)
+	if !ok {
+		return nil, fmt.Errorf("informer %T does not support indexing", r.primary)
+	}
+	indexer := informer.GetIndexer()
+	registeredIndexes.lock.Lock()
+	defer registeredIndexes.lock.Unlock()
+	if registeredIndexes.indexes[indexer][r.index.name] == r.index {
+		return indexer, nil
+	}
+	if err := conflictingIndex(indexer, r.index); err != nil {
+		return nil, err
+	}
+	if err := informer.AddIndexers(cache.Indexers{r.index.name: r.index.indexFunc}); err != nil {
+		return nil, fmt.Errorf("unable to add %q index: %v", r.index.name, err)
+	}
+	if registeredIndexes.indexes[indexer] == nil {
+		registeredIndexes.indexes[indexer] = map[string]*Index{}
+	}
+	registeredIndexes.indexes[indexer][r.index.name] = r.index
+	return indexer, nil
+}
+
+// conflictingIndex returns an error when the index name is registered in the indexer with a different *Index or by other
+// code. The registeredIndexes lock must be held.
+func conflictingIndex(indexer cache.Indexer, index *Index) error {
+	switch registered := registeredIndexes.indexes[indexer][index.name]; {
+	case registered == index:
+		return nil
+	case registered != nil:
+		return fmt.Errorf("index %q is already registered with a different index", index.name)
+	}
+	if _, exists := indexer.GetIndexers()[index.name]; exists {
+		return fmt.Errorf("index %q is already registered by other code", index.name)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mfojtik/controller-framework/pkg/events"
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// annotationRefIndexFunc indexes the config maps by the annotation naming the object in the same namespace.
+func annotationRefIndexFunc(annotation string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		configMap, ok := obj.(*corev1.ConfigMap)
+		if !ok || len(configMap.Annotations[annotation]) == 0 {
+			return nil, nil
+		}
+		return []string{configMap.Namespace + "/" + configMap.Annotations[annotation]}, nil
+	}
+}
+
+func TestWithIndexedReferences(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "first", Annotations: map[string]string{"secretRef": "shared"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "second", Annotations: map[string]string{"secretRef": "shared"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "third", Annotations: map[string]string{"secretRef": "shared"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "unrelated"}},
+	)
+	kubeInformers := informers.NewSharedInformerFactory(client, 0)
+	configMapInformer := kubeInformers.Core().V1().ConfigMaps().Informer()
+	secretInformer := kubeInformers.Core().V1().Secrets().Informer()
+
+	secretRefIndex := NewIndex("secretRef", annotationRefIndexFunc("secretRef"))
+	var lock sync.Mutex
+	synced := sets.New[string]()
+	controller := New().WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		lock.Lock()
+		defer lock.Unlock()
+		synced.Insert(controllerContext.QueueKey())
+		return nil
+	}).WithIndexedReferences(configMapInformer, secretRefIndex, secretInformer).ToController("test", events.NewInMemoryRecorder("test"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	kubeInformers.Start(ctx.Done())
+	kubeInformers.WaitForCacheSync(ctx.Done())
+	go controller.Run(ctx, 1)
+
+	keys := IndexQueueKeysFunc(configMapInformer.GetIndexer(), "secretRef")(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "shared"}})
+	sort.Strings(keys)
+	if expected := []string{"test/first", "test/second"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected keys %v, got %v", expected, keys)
+	}
+
+	// changing the secret syncs the config maps referencing it
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "shared"}}
+	if _, err := client.CoreV1().Secrets("test").Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// the secret is updated until the sync is observed, the fake client does not replay the events missed before the watch starts
+	revision := 0
+	err := wait.PollImmediate(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		lock.Lock()
+		done := synced.HasAll("test/first", "test/second")
+		lock.Unlock()
+		if done {
+			return true, nil
+		}
+		revision++
+		secret.Data = map[string][]byte{"revision": []byte(fmt.Sprint(revision))}
+		_, err := client.CoreV1().Secrets("test").Update(ctx, secret, metav1.UpdateOptions{})
+		return false, err
+	})
+	if err != nil {
+		t.Fatalf("expected the config maps referencing the secret to be synced: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if expected := sets.New("test/first", "test/second"); !synced.Equal(expected) {
+		t.Errorf("expected synced keys %v, got %v", sets.List(expected), sets.List(synced))
+	}
+
+	// the second controller sharing the informer reuses the index
+	if _, err := New().WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}).WithIndexedReferences(configMapInformer, secretRefIndex, secretInformer).Build("other", events.NewInMemoryRecorder("test")); err != nil {
+		t.Errorf("expected the existing index to be reused, got: %v", err)
+	}
+}
+
+func TestWithIndexedReferencesConflictingIndex(t *testing.T) {
+	kubeInformers := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	configMapInformer := kubeInformers.Core().V1().ConfigMaps().Informer()
+	secretInformer := kubeInformers.Core().V1().Secrets().Informer()
+	syncFn := func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}
+	// the index functions are the closures of the same function literal indexing different annotations
+	secretRefIndex := NewIndex("ref", annotationRefIndexFunc("secretRef"))
+	configMapRefIndex := NewIndex("ref", annotationRefIndexFunc("configMapRef"))
+
+	// the conflicting indexes of the same controller are rejected before any index is added
+	_, err := New().WithSync(syncFn).
+		WithIndexedReferences(configMapInformer, secretRefIndex, secretInformer).
+		WithIndexedReferences(configMapInformer, configMapRefIndex).
+		Build("test", events.NewInMemoryRecorder("test"))
+	if err == nil || !strings.Contains(err.Error(), `index "ref" of informer *cache.sharedIndexInformer is already registered with a different index`) {
+		t.Errorf("expected conflicting index error, got: %v", err)
+	}
+	if _, exists := configMapInformer.GetIndexer().GetIndexers()["ref"]; exists {
+		t.Errorf("expected the index not to be added when Build() fails")
+	}
+
+	// the index registered by another controller
+	if _, err := New().WithSync(syncFn).WithIndexedReferences(configMapInformer, secretRefIndex, secretInformer).Build("first", events.NewInMemoryRecorder("test")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = New().WithSync(syncFn).WithIndexedReferences(configMapInformer, configMapRefIndex, secretInformer).Build("second", events.NewInMemoryRecorder("test"))
+	if err == nil || !strings.Contains(err.Error(), `index "ref" is already registered with a different index`) {
+		t.Errorf("expected conflicting index error, got: %v", err)
+	}
+
+	// the index registered by other code
+	if err := configMapInformer.AddIndexers(cache.Indexers{"external": annotationRefIndexFunc("secretRef")}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = New().WithSync(syncFn).WithIndexedReferences(configMapInformer, NewIndex("external", annotationRefIndexFunc("secretRef")), secretInformer).Build("third", events.NewInMemoryRecorder("test"))
+	if err == nil || !strings.Contains(err.Error(), `index "external" is already registered by other code`) {
+		t.Errorf("expected conflicting index error, got: %v", err)
+	}
+}
+
+func TestWithIndexedReferencesNotIndexer(t *testing.T) {
+	var primary cache.SharedInformer = &notIndexedInformer{}
+	_, err := New().WithSync(func(ctx context.Context, controllerContext framework.Context) error {
+		return nil
+	}).WithIndexedReferences(primary, NewIndex("secretRef", annotationRefIndexFunc("secretRef"))).Build("test", events.NewInMemoryRecorder("test"))
+	if err == nil || !strings.Contains(err.Error(), "does not support indexing") {
+		t.Errorf("expected Build() to fail when the primary informer does not support indexing, got: %v", err)
+	}
+}
+
+type notIndexedInformer struct {
+	cache.SharedInformer
+}
@@ -0,0 +1,179 @@
+package factory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// InformerResource describes the informer created and started by the controller factory.
+type InformerResource struct {
+	// Resource is the resource to watch.
+	Resource schema.GroupVersionResource
+
+	// Namespace limits the informer to single namespace. All namespaces are watched when empty.
+	Namespace string
+
+	// LabelSelector and FieldSelector limit the watched objects.
+	LabelSelector string
+	FieldSelector string
+
+	// MetadataOnly makes the informer cache only the object metadata (metav1.PartialObjectMetadata) using the metadata client.
+	// This reduces the memory footprint of controllers that do not need the full objects. Use SharedInformers.FullObjectGetter()
+	// to get the full object when the sync needs it.
+	MetadataOnly bool
+
+	// QueueKeysFunc transforms the observed objects to queue keys. Defaults to DefaultQueueKeysFunc.
+	QueueKeysFunc framework.ObjectQueueKeysFunc
+
+	// Predicate filters the events that trigger the Sync() call. All events trigger the sync when not set.
+	Predicate framework.Predicate
+}
+
+// informerScope identifies the shared informer factory, the informers with the same scope share the factory.
+type informerScope struct {
+	namespace     string
+	labelSelector string
+	fieldSelector string
+}
+
+func (s informerScope) tweakListOptions(options *metav1.ListOptions) {
+	options.LabelSelector = s.labelSelector
+	options.FieldSelector = s.fieldSelector
+}
+
+// SharedInformers creates the informers of InformerResource using the dynamic and metadata shared informer factories.
+// The informers are shared by all controllers that use the same SharedInformers, the informers for the same resource,
+// namespace and selectors are created only once.
+type SharedInformers struct {
+	dynamicClient  dynamic.Interface
+	metadataClient metadata.Interface
+	resync         time.Duration
+
+	dynamicFactories  map[informerScope]dynamicinformer.DynamicSharedInformerFactory
+	metadataFactories map[informerScope]metadatainformer.SharedInformerFactory
+
+	// references is the number of Start() calls with the stop channel not closed yet
+	references int
+	// stopCh stops the informers when the last reference is released
+	stopCh chan struct{}
+
+	lock sync.Mutex
+}
+
+// NewSharedInformers returns shared informers using the dynamic client for the full objects and the metadata client for
+// the metadata only informers. Any of the clients can be nil if the informers of that kind are not used.
+func NewSharedInformers(dynamicClient dynamic.Interface, metadataClient metadata.Interface, resync time.Duration) *SharedInformers {
+	return &SharedInformers{
+		dynamicClient:     dynamicClient,
+		metadataClient:    metadataClient,
+		resync:            resync,
+		dynamicFactories:  map[informerScope]dynamicinformer.DynamicSharedInformerFactory{},
+		metadataFactories: map[informerScope]metadatainformer.SharedInformerFactory{},
+	}
+}
+
+// ForResource returns the shared informer for the resource.
+func (s *SharedInformers) ForResource(resource InformerResource) (informers.GenericInformer, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	scope := informerScope{namespace: resource.Namespace, labelSelector: resource.LabelSelector, fieldSelector: resource.FieldSelector}
+	if resource.MetadataOnly {
+		if s.metadataClient == nil {
+			return nil, fmt.Errorf("metadata client is required for metadata only informer of %s", resource.Resource.String())
+		}
+		informerFactory, ok := s.metadataFactories[scope]
+		if !ok {
+			informerFactory = metadatainformer.NewFilteredSharedInformerFactory(s.metadataClient, s.resync, scope.namespace, scope.tweakListOptions)
+			s.metadataFactories[scope] = informerFactory
+		}
+		return informerFactory.ForResource(resource.Resource), nil
+	}
+	if s.dynamicClient == nil {
+		return nil, fmt.Errorf("dynamic client is required for informer of %s", resource.Resource.String())
+	}
+	informerFactory, ok := s.dynamicFactories[scope]
+	if !ok {
+		informerFactory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(s.dynamicClient, s.resync, scope.namespace, scope.tweakListOptions)
+		s.dynamicFactories[scope] = informerFactory
+	}
+	return informerFactory.ForResource(resource.Resource), nil
+}
+
+// Start starts all informers created so far. The informers that are already running are not started again.
+// The informers are shared, so they are not tied to a single stop channel: every Start() call holds the informers running
+// until its stop channel is closed and the informers are stopped when the stop channels of all Start() calls are closed.
+// The controllers using the informers call Start() with their own stop channel, so a controller stopped at runtime does not
+// stop the informers used by other controllers. Call Start() with the process stop channel to keep the informers running
+// even when no controller runs.
+// Once the informers are stopped, the next ForResource() calls create new informers. The controllers built with the
+// stopped informers have to be built again.
+func (s *SharedInformers) Start(stopCh <-chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.references == 0 {
+		s.stopCh = make(chan struct{})
+	}
+	s.references++
+	for _, informerFactory := range s.dynamicFactories {
+		informerFactory.Start(s.stopCh)
+	}
+	for _, informerFactory := range s.metadataFactories {
+		informerFactory.Start(s.stopCh)
+	}
+	go func() {
+		<-stopCh
+		s.release()
+	}()
+}
+
+// release stops the informers when the last reference is released.
+func (s *SharedInformers) release() {
+	s.lock.Lock()
+	s.references--
+	if s.references > 0 {
+		s.lock.Unlock()
+		return
+	}
+	close(s.stopCh)
+	dynamicFactories, metadataFactories := s.dynamicFactories, s.metadataFactories
+	// the stopped informers cannot be started again
+	s.dynamicFactories = map[informerScope]dynamicinformer.DynamicSharedInformerFactory{}
+	s.metadataFactories = map[informerScope]metadatainformer.SharedInformerFactory{}
+	s.lock.Unlock()
+
+	for _, informerFactory := range dynamicFactories {
+		informerFactory.Shutdown()
+	}
+	for _, informerFactory := range metadataFactories {
+		informerFactory.Shutdown()
+	}
+}
+
+// WithSharedInformers sets the shared informers used to create the informers registered via WithResourceInformers.
+// Use the same shared informers for multiple controllers to share the informers between them.
+// If not set, Build() returns an error when resource informers are registered.
+func (f *Factory) WithSharedInformers(sharedInformers *SharedInformers) *Factory {
+	f.sharedInformers = sharedInformers
+	return f
+}
+
+// WithResourceInformers makes the factory create the informers for the resources using the shared informers (see WithSharedInformers).
+// The informers are started when the controller Run() is called, so there is no need to start the informer factories manually.
+// The shared informers keep running until all controllers using them are stopped (see SharedInformers.Start).
+// The listers of the informers are available to the sync function via framework.ListerFor() or, converted to the typed
+// objects, via framework.TypedListerFor(). When multiple informers for the same resource are registered, the lister of the last one is used.
+func (f *Factory) WithResourceInformers(resources ...InformerResource) *Factory {
+	f.resourceInformers = append(f.resourceInformers, resources...)
+	return f
+}
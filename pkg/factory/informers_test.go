@@ -0,0 +1,162 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+
+	"github.com/mfojtik/controller-framework/pkg/events"
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+func TestWithResourceInformers(t *testing.T) {
+	secretsResource := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	configMapsResource := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "secret"}}
+	configMap := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "config"},
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		secretsResource: "SecretList",
+	}, secret)
+	metadataClient := metadatafake.NewSimpleMetadataClient(scheme, configMap)
+
+	synced := make(chan []string, 1)
+	c := New().WithSharedInformers(NewSharedInformers(dynamicClient, metadataClient, 0)).WithResourceInformers(
+		InformerResource{Resource: secretsResource, Namespace: "test"},
+		InformerResource{Resource: configMapsResource, MetadataOnly: true},
+	).WithSync(func(ctx context.Context, syncContext framework.Context) error {
+		var names []string
+		for _, resource := range []schema.GroupVersionResource{secretsResource, configMapsResource} {
+			lister, err := framework.ListerFor(syncContext, resource)
+			if err != nil {
+				return err
+			}
+			objs, err := lister.List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			for _, obj := range objs {
+				names = append(names, obj.(metav1.Object).GetName())
+			}
+		}
+		secrets, err := framework.TypedListerFor[*corev1.Secret](syncContext, secretsResource)
+		if err != nil {
+			return err
+		}
+		if typedSecret, err := secrets.Get("test", "secret"); err != nil || typedSecret.Name != "secret" {
+			return fmt.Errorf("expected the typed secret, got %v: %v", typedSecret, err)
+		}
+		configMaps, err := framework.TypedListerFor[*metav1.PartialObjectMetadata](syncContext, configMapsResource)
+		if err != nil {
+			return err
+		}
+		if typedConfigMaps, err := configMaps.List(labels.Everything()); err != nil || len(typedConfigMaps) != 1 {
+			return fmt.Errorf("expected the typed config map metadata, got %v: %v", typedConfigMaps, err)
+		}
+		select {
+		case synced <- names:
+		default:
+		}
+		return nil
+	}).ToController("test", events.NewInMemoryRecorder("test"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the informers are started by the controller
+	go c.Run(ctx, 1)
+
+	select {
+	case names := <-synced:
+		if len(names) != 2 || names[0] != "secret" || names[1] != "config" {
+			t.Errorf("expected the listers to return the secret and the config map, got %v", names)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for sync")
+	}
+}
+
+func TestSharedInformersOutliveStoppedController(t *testing.T) {
+	secretsResource := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	// the objects are kept unstructured, the watch events of typed objects are rejected by dynamic informers
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		secretsResource: "SecretList",
+	})
+	sharedInformers := NewSharedInformers(dynamicClient, nil, 0)
+
+	newController := func(name string, synced chan string) framework.Controller {
+		return New().WithSharedInformers(sharedInformers).WithResourceInformers(InformerResource{
+			Resource: secretsResource,
+			QueueKeysFunc: func(obj runtime.Object) []string {
+				return []string{obj.(metav1.Object).GetName()}
+			},
+		}).WithSync(func(ctx context.Context, syncContext framework.Context) error {
+			synced <- syncContext.QueueKey()
+			return nil
+		}).ToController(name, events.NewInMemoryRecorder(name))
+	}
+	firstSynced, secondSynced := make(chan string, 10), make(chan string, 10)
+	first, second := newController("first", firstSynced), newController("second", secondSynced)
+
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		first.Run(firstCtx, 1)
+	}()
+	secondCtx, secondCancel := context.WithCancel(context.Background())
+	defer secondCancel()
+	go second.Run(secondCtx, 1)
+
+	createSecret := func(name string) {
+		t.Helper()
+		secret := &unstructured.Unstructured{}
+		secret.SetAPIVersion("v1")
+		secret.SetKind("Secret")
+		secret.SetNamespace("test")
+		secret.SetName(name)
+		if _, err := dynamicClient.Resource(secretsResource).Namespace("test").Create(context.Background(), secret, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectSync := func(synced chan string, key string) {
+		t.Helper()
+		select {
+		case got := <-synced:
+			if got != key {
+				t.Errorf("expected sync of %q, got %q", key, got)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected sync of %q", key)
+		}
+	}
+
+	createSecret("a")
+	expectSync(firstSynced, "a")
+	expectSync(secondSynced, "a")
+
+	// stopping the first controller does not stop the informers used by the second one
+	firstCancel()
+	<-firstDone
+	createSecret("b")
+	expectSync(secondSynced, "b")
+}
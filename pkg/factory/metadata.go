@@ -0,0 +1,91 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// ObjectMetaQueueKeysFunc is used to make queue keys out of the object metadata. It works with both the full objects and
+// the metav1.PartialObjectMetadata observed by the metadata only informers.
+type ObjectMetaQueueKeysFunc func(obj metav1.Object) []string
+
+// MetaQueueKeysFunc adapts the queue keys function working on the object metadata to framework.ObjectQueueKeysFunc.
+func MetaQueueKeysFunc(queueKeysFn ObjectMetaQueueKeysFunc) framework.ObjectQueueKeysFunc {
+	return func(obj runtime.Object) []string {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to get metadata of %T: %v", obj, err))
+			return nil
+		}
+		return queueKeysFn(objMeta)
+	}
+}
+
+// NamespaceNameQueueKeysFunc returns the "namespace/name" key of the object ("name" for cluster scoped objects).
+var NamespaceNameQueueKeysFunc = MetaQueueKeysFunc(func(obj metav1.Object) []string {
+	if len(obj.GetNamespace()) == 0 {
+		return []string{obj.GetName()}
+	}
+	return []string{obj.GetNamespace() + "/" + obj.GetName()}
+})
+
+// FullObjectGetter gets the full objects of the resource watched by metadata only informer.
+// The metadata cache is checked first, so the API server is only called for objects that exist.
+type FullObjectGetter struct {
+	resource schema.GroupVersionResource
+	lister   cache.GenericLister
+	client   dynamic.Interface
+}
+
+// NewFullObjectGetter returns getter of the full objects, the lister is usually the lister of the metadata only informer.
+func NewFullObjectGetter(resource schema.GroupVersionResource, lister cache.GenericLister, client dynamic.Interface) *FullObjectGetter {
+	return &FullObjectGetter{resource: resource, lister: lister, client: client}
+}
+
+// FullObjectGetter returns the getter of the full objects of the metadata only informer resource.
+// The dynamic client is used to get the full objects.
+func (s *SharedInformers) FullObjectGetter(resource InformerResource) (*FullObjectGetter, error) {
+	if s.dynamicClient == nil {
+		return nil, fmt.Errorf("dynamic client is required to get full objects of %s", resource.Resource.String())
+	}
+	resource.MetadataOnly = true
+	informer, err := s.ForResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	return NewFullObjectGetter(resource.Resource, informer.Lister(), s.dynamicClient), nil
+}
+
+// Get returns the full object. The NotFound error is returned without calling the API server when the object is not in the metadata cache.
+func (g *FullObjectGetter) Get(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	var err error
+	if len(namespace) > 0 {
+		_, err = g.lister.ByNamespace(namespace).Get(name)
+	} else {
+		_, err = g.lister.Get(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g.client.Resource(g.resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// GetInto gets the full object and converts it into the given typed object (eg. *corev1.Secret).
+func (g *FullObjectGetter) GetInto(ctx context.Context, namespace, name string, into runtime.Object) error {
+	obj, err := g.Get(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), into)
+}
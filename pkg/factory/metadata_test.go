@@ -0,0 +1,61 @@
+package factory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNamespaceNameQueueKeysFunc(t *testing.T) {
+	partial := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "secret"}}
+	if keys := NamespaceNameQueueKeysFunc(partial); !reflect.DeepEqual(keys, []string{"test/secret"}) {
+		t.Errorf("expected namespaced key, got %v", keys)
+	}
+	clusterScoped := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+	if keys := NamespaceNameQueueKeysFunc(clusterScoped); !reflect.DeepEqual(keys, []string{"node"}) {
+		t.Errorf("expected cluster scoped key, got %v", keys)
+	}
+}
+
+func TestFullObjectGetter(t *testing.T) {
+	secretsResource := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "secret"}, Data: map[string][]byte{"key": []byte("value")}}
+	uncached := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "uncached"}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme, secret, uncached)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(&metav1.PartialObjectMetadata{ObjectMeta: secret.ObjectMeta}); err != nil {
+		t.Fatal(err)
+	}
+	getter := NewFullObjectGetter(secretsResource, cache.NewGenericLister(indexer, secretsResource.GroupResource()), dynamicClient)
+
+	fullSecret := &corev1.Secret{}
+	if err := getter.GetInto(context.Background(), "test", "secret", fullSecret); err != nil {
+		t.Fatal(err)
+	}
+	if string(fullSecret.Data["key"]) != "value" {
+		t.Errorf("expected full secret, got %#v", fullSecret)
+	}
+
+	// objects not present in the metadata cache are not fetched
+	if _, err := getter.Get(context.Background(), "test", "uncached"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected NotFound error, got %v", err)
+	}
+	for _, action := range dynamicClient.Actions() {
+		if action.GetVerb() == "get" && action.(interface{ GetName() string }).GetName() == "uncached" {
+			t.Errorf("expected the object missing in the cache not to be fetched")
+		}
+	}
+}
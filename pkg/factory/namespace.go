@@ -0,0 +1,295 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// NamespaceTransition describes how the namespace selection changed since the last successful sync of the namespace.
+type NamespaceTransition string
+
+const (
+	// NamespaceUnchanged means the namespace is selected (or not selected) as it was during the last successful sync.
+	NamespaceUnchanged NamespaceTransition = ""
+	// NamespaceEntered means the namespace was created or changed to match the selection.
+	NamespaceEntered NamespaceTransition = "Entered"
+	// NamespaceLeft means the namespace was deleted or changed to not match the selection.
+	NamespaceLeft NamespaceTransition = "Left"
+)
+
+// NamespaceSelection selects the interesting namespaces by names and label selector. The selection can be changed at runtime
+// (eg. from a custom resource), the namespaces that entered or left the selection are synced.
+type NamespaceSelection struct {
+	names    sets.Set[string]
+	selector labels.Selector
+
+	// selected are the observed namespaces matching the selection
+	selected sets.Set[string]
+	// acknowledged are the selected namespaces as of their last successful sync
+	acknowledged sets.Set[string]
+	// syncing are the transitions of the namespaces being synced, as observed when the sync started
+	syncing map[string]NamespaceTransition
+
+	// controller, store and enqueue are set when the selection is registered in the controller
+	controller string
+	store      cache.Store
+	enqueue    func(name string)
+
+	lock sync.Mutex
+}
+
+// NewNamespaceSelection returns the selection of namespaces matching the selector or having one of the names.
+// The nil selector does not match any namespace.
+func NewNamespaceSelection(selector labels.Selector, names ...string) *NamespaceSelection {
+	return &NamespaceSelection{
+		names:        sets.New[string](names...),
+		selector:     selector,
+		selected:     sets.New[string](),
+		acknowledged: sets.New[string](),
+		syncing:      map[string]NamespaceTransition{},
+	}
+}
+
+// SetSelector changes the label selector, the namespaces that entered or left the selection are synced.
+func (s *NamespaceSelection) SetSelector(selector labels.Selector) {
+	s.lock.Lock()
+	s.selector = selector
+	s.lock.Unlock()
+	s.reevaluate()
+}
+
+// SetNames changes the names of selected namespaces, the namespaces that entered or left the selection are synced.
+func (s *NamespaceSelection) SetNames(names ...string) {
+	s.lock.Lock()
+	s.names = sets.New[string](names...)
+	s.lock.Unlock()
+	s.reevaluate()
+}
+
+// Selected returns true if the namespace is selected.
+func (s *NamespaceSelection) Selected(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.selected.Has(name)
+}
+
+// Transition returns how the selection of the namespace changed since the last successful sync of the namespace.
+// The transition is reported until the sync of the namespace succeeds, so the failed syncs observe the same transition when retried.
+// While the namespace is synced, the transition observed when the sync started is returned. When the selection changes
+// during the sync, the namespace is synced again with the new transition.
+func (s *NamespaceSelection) Transition(name string) NamespaceTransition {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if transition, ok := s.syncing[name]; ok {
+		return transition
+	}
+	return s.transitionLocked(name)
+}
+
+func (s *NamespaceSelection) transitionLocked(name string) NamespaceTransition {
+	switch selected, acknowledged := s.selected.Has(name), s.acknowledged.Has(name); {
+	case selected && !acknowledged:
+		return NamespaceEntered
+	case !selected && acknowledged:
+		return NamespaceLeft
+	default:
+		return NamespaceUnchanged
+	}
+}
+
+// startSync records the transition of the namespace observed by the sync that is starting.
+func (s *NamespaceSelection) startSync(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.syncing[name] = s.transitionLocked(name)
+}
+
+// finishSync acknowledges the transition observed by the sync when the sync succeeded. The changes of the selection that
+// happened during the sync are not acknowledged, they are reported to the next sync of the namespace.
+func (s *NamespaceSelection) finishSync(name string, succeeded bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	transition := s.syncing[name]
+	delete(s.syncing, name)
+	if !succeeded {
+		return
+	}
+	switch transition {
+	case NamespaceEntered:
+		s.acknowledged.Insert(name)
+	case NamespaceLeft:
+		s.acknowledged.Delete(name)
+	}
+}
+
+// register binds the selection to the controller. The selection tracks the transitions acknowledged by the syncs of
+// a single controller, so it cannot be shared by multiple controllers.
+func (s *NamespaceSelection) register(controller string, store cache.Store, enqueue func(name string)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.controller, s.store, s.enqueue = controller, store, enqueue
+}
+
+// registeredController returns the name of the controller the selection is registered in.
+func (s *NamespaceSelection) registeredController() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.controller
+}
+
+func (s *NamespaceSelection) matchesLocked(namespace *corev1.Namespace) bool {
+	if s.names.Has(namespace.Name) {
+		return true
+	}
+	return s.selector != nil && s.selector.Matches(labels.Set(namespace.Labels))
+}
+
+// observe updates the selection of the namespace and returns true if the namespace should be synced, which is when the
+// namespace is selected or it just left the selection.
+func (s *NamespaceSelection) observe(namespace *corev1.Namespace, deleted bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	wasSelected := s.selected.Has(namespace.Name)
+	isSelected := !deleted && s.matchesLocked(namespace)
+	if isSelected {
+		s.selected.Insert(namespace.Name)
+	} else {
+		s.selected.Delete(namespace.Name)
+	}
+	return wasSelected || isSelected
+}
+
+// reevaluate updates the selection of all known namespaces and enqueues the namespaces that entered or left the selection.
+func (s *NamespaceSelection) reevaluate() {
+	s.lock.Lock()
+	store, enqueue := s.store, s.enqueue
+	s.lock.Unlock()
+	if store == nil || enqueue == nil {
+		return
+	}
+	for _, obj := range store.List() {
+		namespace, ok := obj.(*corev1.Namespace)
+		if !ok {
+			continue
+		}
+		s.lock.Lock()
+		wasSelected := s.selected.Has(namespace.Name)
+		isSelected := s.matchesLocked(namespace)
+		if isSelected {
+			s.selected.Insert(namespace.Name)
+		} else {
+			s.selected.Delete(namespace.Name)
+		}
+		s.lock.Unlock()
+		if wasSelected != isSelected {
+			enqueue(namespace.Name)
+		}
+	}
+}
+
+// eventHandler returns handler that updates the selection and enqueues the namespace name.
+func (s *NamespaceSelection) eventHandler(enqueue func(name string)) cache.ResourceEventHandler {
+	observe := func(obj interface{}, deleted bool) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		namespace, ok := obj.(*corev1.Namespace)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("object %T is not a namespace", obj))
+			return
+		}
+		if s.observe(namespace, deleted) {
+			enqueue(namespace.Name)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			observe(obj, false)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			observe(newObj, false)
+		},
+		DeleteFunc: func(obj interface{}) {
+			observe(obj, true)
+		},
+	}
+}
+
+// WithNamespaceSelection is used to sync the namespaces selected by the selection. The namespace name is used as the queue key.
+// The sync is triggered when a selected namespace changes and when a namespace enters or leaves the selection, because it
+// was created, deleted, relabeled or because the selection itself changed (see NamespaceSelection.SetSelector).
+// The sync function can get the transition of the namespace via NamespaceSelection.Transition(syncContext.QueueKey()).
+// The selection tracks the transitions acknowledged by the controller, so each controller needs its own selection.
+// Do not use this to register non-namespace informers.
+func (f *Factory) WithNamespaceSelection(informer framework.Informer, selection *NamespaceSelection) *Factory {
+	f.namespaceSelections = append(f.namespaceSelections, namespaceSelection{informer: informer, selection: selection})
+	return f
+}
+
+type namespaceSelection struct {
+	informer  framework.Informer
+	selection *NamespaceSelection
+}
+
+// startNamespaceSyncs records the transitions of the namespace observed by the sync that is starting.
+func startNamespaceSyncs(selections []namespaceSelection, key string) {
+	for _, s := range selections {
+		s.selection.startSync(key)
+	}
+}
+
+// finishNamespaceSyncs acknowledges the transitions observed by the sync of the key if the sync succeeded.
+func finishNamespaceSyncs(selections []namespaceSelection, key string, succeeded bool) {
+	for _, s := range selections {
+		s.selection.finishSync(key, succeeded)
+	}
+}
+
+// acknowledgingSync wraps the sync function to acknowledge the namespace transitions observed by the successful sync.
+func acknowledgingSync(selections []namespaceSelection, syncFn framework.ControllerSyncFn) framework.ControllerSyncFn {
+	if syncFn == nil {
+		return nil
+	}
+	return func(ctx context.Context, controllerContext framework.Context) error {
+		key := controllerContext.QueueKey()
+		startNamespaceSyncs(selections, key)
+		// the sync is finished even when the sync function panics, the panicking sync is counted as failed
+		succeeded := false
+		defer func() { finishNamespaceSyncs(selections, key, succeeded) }()
+		err := syncFn(ctx, controllerContext)
+		succeeded = err == nil
+		return err
+	}
+}
+
+// acknowledgingBatchSync wraps the batch sync function to acknowledge the namespace transitions observed by the successful sync of the keys.
+func acknowledgingBatchSync(selections []namespaceSelection, batchSyncFn framework.ControllerBatchSyncFn) framework.ControllerBatchSyncFn {
+	if batchSyncFn == nil {
+		return nil
+	}
+	return func(ctx context.Context, controllerContext framework.Context, keys []string) map[string]error {
+		for _, key := range keys {
+			startNamespaceSyncs(selections, key)
+		}
+		// the syncs are finished even when the batch sync function panics, the panicking sync is counted as failed for all keys
+		var errs map[string]error
+		returned := false
+		defer func() {
+			for _, key := range keys {
+				finishNamespaceSyncs(selections, key, returned && errs[key] == nil)
+			}
+		}()
+		errs = batchSyncFn(ctx, controllerContext, keys)
+		returned = true
+		return errs
+	}
+}
@@ -0,0 +1,175 @@
+package factory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	context2 "github.com/mfojtik/controller-framework/pkg/context"
+	"github.com/mfojtik/controller-framework/pkg/events"
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+func TestWithNamespaceSelection(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	kubeInformers := informers.NewSharedInformerFactory(kubeClient, 0)
+	namespaceInformer := kubeInformers.Core().V1().Namespaces().Informer()
+
+	selection := NewNamespaceSelection(labels.SelectorFromSet(labels.Set{"env": "prod"}))
+	transitions := make(chan string, 10)
+	c := New().WithNamespaceSelection(namespaceInformer, selection).WithSync(func(ctx context.Context, syncContext framework.Context) error {
+		transitions <- syncContext.QueueKey() + ":" + string(selection.Transition(syncContext.QueueKey()))
+		return nil
+	}).ToController("test", events.NewInMemoryRecorder("test"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	kubeInformers.Start(ctx.Done())
+	go c.Run(ctx, 1)
+
+	expect := func(expected ...string) {
+		t.Helper()
+		got := sets.New[string]()
+		for len(got) < len(expected) {
+			select {
+			case transition := <-transitions:
+				got.Insert(transition)
+			case <-time.After(10 * time.Second):
+				t.Fatalf("expected transitions %v, got %v", expected, sets.List(got))
+			}
+		}
+		if !got.Equal(sets.New[string](expected...)) {
+			t.Fatalf("expected transitions %v, got %v", expected, sets.List(got))
+		}
+	}
+	namespace := func(name, env string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"env": env}}}
+	}
+
+	if _, err := kubeClient.CoreV1().Namespaces().Create(ctx, namespace("a", "prod"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expect("a:Entered")
+
+	if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, namespace("a", "dev"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expect("a:Left")
+
+	// the namespaces that are not selected are not synced
+	if _, err := kubeClient.CoreV1().Namespaces().Create(ctx, namespace("b", "dev"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().Namespaces().Create(ctx, namespace("c", "prod"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expect("c:Entered")
+
+	// changing the selection syncs the namespaces that entered or left it
+	selection.SetSelector(labels.SelectorFromSet(labels.Set{"env": "dev"}))
+	expect("a:Entered", "b:Entered", "c:Left")
+	if !selection.Selected("a") || selection.Selected("c") {
+		t.Errorf("expected the selection to be updated")
+	}
+}
+
+func TestNamespaceSelectionChangedDuringSync(t *testing.T) {
+	selection := NewNamespaceSelection(labels.SelectorFromSet(labels.Set{"env": "prod"}))
+	namespace := func(env string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"env": env}}}
+	}
+
+	selection.observe(namespace("prod"), false)
+	selection.startSync("a")
+	// the namespace leaves the selection while the sync of its entry runs
+	selection.observe(namespace("dev"), false)
+	if transition := selection.Transition("a"); transition != NamespaceEntered {
+		t.Errorf("expected the sync to observe the transition from its start, got %q", transition)
+	}
+	selection.finishSync("a", true)
+	if transition := selection.Transition("a"); transition != NamespaceLeft {
+		t.Errorf("expected the next sync to observe the namespace left, got %q", transition)
+	}
+
+	// the failed sync does not acknowledge the transition
+	selection.startSync("a")
+	selection.finishSync("a", false)
+	if transition := selection.Transition("a"); transition != NamespaceLeft {
+		t.Errorf("expected the transition to be reported until the sync succeeds, got %q", transition)
+	}
+}
+
+func TestNamespaceSelectionSyncPanics(t *testing.T) {
+	selection := NewNamespaceSelection(labels.SelectorFromSet(labels.Set{"env": "prod"}))
+	namespace := func(env string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"env": env}}}
+	}
+	selections := []namespaceSelection{{selection: selection}}
+	syncContext := context2.New("test", events.NewInMemoryRecorder("test")).(context2.Context).WithQueueKey("a")
+
+	tests := []struct {
+		name string
+		sync func()
+	}{
+		{
+			name: "sync",
+			sync: func() {
+				syncFn := acknowledgingSync(selections, func(ctx context.Context, syncContext framework.Context) error {
+					panic("sync failed")
+				})
+				_ = syncFn(context.TODO(), syncContext)
+			},
+		},
+		{
+			name: "batch sync",
+			sync: func() {
+				batchSyncFn := acknowledgingBatchSync(selections, func(ctx context.Context, syncContext framework.Context, keys []string) map[string]error {
+					panic("sync failed")
+				})
+				_ = batchSyncFn(context.TODO(), syncContext, []string{"a"})
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selection.observe(namespace("prod"), false)
+			func() {
+				defer func() { _ = recover() }()
+				test.sync()
+			}()
+			if transition := selection.Transition("a"); transition != NamespaceEntered {
+				t.Errorf("expected the panicking sync not to acknowledge the transition, got %q", transition)
+			}
+			// the panicking sync does not pin the transition observed when it started
+			selection.observe(namespace("dev"), false)
+			if transition := selection.Transition("a"); transition != NamespaceUnchanged {
+				t.Errorf("expected the transition to follow the selection after the panicking sync, got %q", transition)
+			}
+		})
+	}
+}
+
+func TestNamespaceSelectionSharedByControllers(t *testing.T) {
+	kubeInformers := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	namespaceInformer := kubeInformers.Core().V1().Namespaces().Informer()
+	selection := NewNamespaceSelection(nil, "a")
+	syncFn := func(ctx context.Context, syncContext framework.Context) error {
+		return nil
+	}
+
+	if _, err := New().WithNamespaceSelection(namespaceInformer, selection).WithSync(syncFn).Build("first", events.NewInMemoryRecorder("test")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := New().WithNamespaceSelection(namespaceInformer, selection).WithSync(syncFn).Build("second", events.NewInMemoryRecorder("test"))
+	if err == nil || !strings.Contains(err.Error(), `selection is already used by "first" controller`) {
+		t.Errorf("expected the shared selection to be rejected, got: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package factory
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// maxOwnerDepth limits the number of owner references followed, this protects from owner reference cycles.
+const maxOwnerDepth = 10
+
+// OwnerLookupFunc returns the object referenced by the owner reference of an object in the given namespace.
+// It is used to walk through intermediate owners (eg. Pod -> ReplicaSet -> Deployment), usually implemented using listers.
+// When the object does not exist, the NotFound error should be returned.
+type OwnerLookupFunc func(namespace string, ownerRef metav1.OwnerReference) (metav1.Object, error)
+
+type ownerOptions struct {
+	clusterScoped bool
+}
+
+// OwnerOption configures the queue keys function returned by OwnerQueueKeysFunc.
+type OwnerOption func(*ownerOptions)
+
+// ClusterScopedOwner makes the owner keys the bare "name" of the owner, use it when the owner kind is cluster scoped
+// (eg. a cluster scoped custom resource owning the namespaced objects).
+func ClusterScopedOwner() OwnerOption {
+	return func(o *ownerOptions) {
+		o.clusterScoped = true
+	}
+}
+
+// OwnerQueueKeysFunc returns queue keys function that maps the object to the "namespace/name" key of its controller owner
+// (the owner reference with controller set to true) of the given group and kind.
+// If the lookup is not nil and the controller owner is of a different kind, the lookup is used to get the owner and its
+// controller owner is checked, up to ten levels. Objects without such owner produce no keys.
+// The owner is expected to live in the object namespace, unless the ClusterScopedOwner option is passed. Cluster scoped
+// owners (and the owners of cluster scoped objects) produce the "name" key.
+func OwnerQueueKeysFunc(ownerGroupKind schema.GroupKind, lookup OwnerLookupFunc, options ...OwnerOption) framework.ObjectQueueKeysFunc {
+	o := &ownerOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	return func(obj runtime.Object) []string {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to get owner of %T: %v", obj, err))
+			return nil
+		}
+		for depth := 0; depth < maxOwnerDepth; depth++ {
+			ownerRef := metav1.GetControllerOf(objMeta)
+			if ownerRef == nil {
+				return nil
+			}
+			ownerGroupVersion, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+			if err != nil {
+				utilruntime.HandleError(fmt.Errorf("invalid owner reference of %s/%s: %v", objMeta.GetNamespace(), objMeta.GetName(), err))
+				return nil
+			}
+			if ownerGroupVersion.Group == ownerGroupKind.Group && ownerRef.Kind == ownerGroupKind.Kind {
+				if o.clusterScoped || len(objMeta.GetNamespace()) == 0 {
+					return []string{ownerRef.Name}
+				}
+				return []string{objMeta.GetNamespace() + "/" + ownerRef.Name}
+			}
+			if lookup == nil {
+				return nil
+			}
+			owner, err := lookup(objMeta.GetNamespace(), *ownerRef)
+			if err != nil {
+				if !apierrors.IsNotFound(err) {
+					utilruntime.HandleError(fmt.Errorf("unable to get owner %s %q of %s/%s: %v", ownerRef.Kind, ownerRef.Name, objMeta.GetNamespace(), objMeta.GetName(), err))
+				}
+				return nil
+			}
+			objMeta = owner
+		}
+		return nil
+	}
+}
+
+// WithOwnedInformers is used to register event handlers for informers of objects owned by the controller resource.
+// The events are mapped to the "namespace/name" key of the controller owner of the given group and kind, so the Sync()
+// function is called for the owner when any of its children changes. The deleted objects (including tombstones) are mapped as well.
+// The owner is expected to be namespaced, use WithInformersQueueKeysFunc with OwnerQueueKeysFunc and the ClusterScopedOwner
+// option for cluster scoped owners.
+func (f *Factory) WithOwnedInformers(ownerGroupKind schema.GroupKind, informers ...framework.Informer) *Factory {
+	return f.WithInformersQueueKeysFunc(OwnerQueueKeysFunc(ownerGroupKind, nil), informers...)
+}
+
+// WithIndirectlyOwnedInformers is like WithOwnedInformers, but the owner is found by walking multiple levels of controller
+// owners using the lookup (eg. the Deployment owning the ReplicaSet owning the Pod).
+func (f *Factory) WithIndirectlyOwnedInformers(ownerGroupKind schema.GroupKind, lookup OwnerLookupFunc, informers ...framework.Informer) *Factory {
+	return f.WithInformersQueueKeysFunc(OwnerQueueKeysFunc(ownerGroupKind, lookup), informers...)
+}
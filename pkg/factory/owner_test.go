@@ -0,0 +1,76 @@
+package factory
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mfojtik/controller-framework/pkg/context"
+	"github.com/mfojtik/controller-framework/pkg/events"
+)
+
+func TestOwnerQueueKeysFunc(t *testing.T) {
+	deploymentGroupKind := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	isController := true
+	ownedBy := func(apiVersion, kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, Controller: &isController}}
+	}
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "web-1", OwnerReferences: ownedBy("apps/v1", "Deployment", "web")}}
+	lookup := func(namespace string, ownerRef metav1.OwnerReference) (metav1.Object, error) {
+		if namespace == "test" && ownerRef.Kind == "ReplicaSet" && ownerRef.Name == replicaSet.Name {
+			return replicaSet, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: ownerRef.Kind}, ownerRef.Name)
+	}
+	pod := func(owners []metav1.OwnerReference) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "pod", OwnerReferences: owners}}
+	}
+
+	tests := []struct {
+		name     string
+		obj      *corev1.Pod
+		lookup   OwnerLookupFunc
+		options  []OwnerOption
+		expected []string
+	}{
+		{name: "direct owner", obj: pod(ownedBy("apps/v1", "Deployment", "web")), expected: []string{"test/web"}},
+		{name: "owner of other group", obj: pod(ownedBy("example.com/v1", "Deployment", "web"))},
+		{name: "not controller owner", obj: pod([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}})},
+		{name: "no owner", obj: pod(nil)},
+		{name: "indirect owner without lookup", obj: pod(ownedBy("apps/v1", "ReplicaSet", "web-1"))},
+		{name: "indirect owner", obj: pod(ownedBy("apps/v1", "ReplicaSet", "web-1")), lookup: lookup, expected: []string{"test/web"}},
+		{name: "missing intermediate owner", obj: pod(ownedBy("apps/v1", "ReplicaSet", "web-2")), lookup: lookup},
+		{name: "cluster scoped owner", obj: pod(ownedBy("apps/v1", "Deployment", "web")), options: []OwnerOption{ClusterScopedOwner()}, expected: []string{"web"}},
+		{name: "indirect cluster scoped owner", obj: pod(ownedBy("apps/v1", "ReplicaSet", "web-1")), lookup: lookup, options: []OwnerOption{ClusterScopedOwner()}, expected: []string{"web"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if keys := OwnerQueueKeysFunc(deploymentGroupKind, test.lookup, test.options...)(test.obj); !reflect.DeepEqual(keys, test.expected) {
+				t.Errorf("expected keys %v, got %v", test.expected, keys)
+			}
+		})
+	}
+}
+
+func TestOwnerQueueKeysFuncTombstone(t *testing.T) {
+	isController := true
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "pod", OwnerReferences: []metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Controller: &isController},
+	}}}
+	syncContext := context.New("test", events.NewInMemoryRecorder("test")).(context.Context)
+	handler := syncContext.PredicateEventHandler(OwnerQueueKeysFunc(schema.GroupKind{Group: "apps", Kind: "Deployment"}, nil), nil)
+
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "test/pod", Obj: pod})
+	if syncContext.Queue().Len() != 1 {
+		t.Fatalf("expected the owner key to be queued, got %d keys", syncContext.Queue().Len())
+	}
+	if key, _ := syncContext.Queue().Get(); key != "test/web" {
+		t.Errorf("expected the owner key %q, got %q", "test/web", key)
+	}
+}
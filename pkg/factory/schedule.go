@@ -0,0 +1,78 @@
+package factory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// timeZonePrefixes are the prefixes allowed to specify the time zone of the schedule.
+var timeZonePrefixes = []string{"CRON_TZ=", "TZ="}
+
+// secondsScheduleParser parse the schedules with the seconds field.
+var secondsScheduleParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseSchedule parses the Cron syntax schedule used by ResyncSchedule.
+// The schedule can be:
+//
+//   - standard cron spec with five fields ("0 3 * * *")
+//   - cron spec with additional seconds field as the first field ("30 0 3 * * *")
+//   - descriptor ("@hourly", "@every 1h30m")
+//
+// Any of the above can be prefixed with "CRON_TZ=<zone> " or "TZ=<zone> " to run the schedule in the given IANA time zone
+// (for example "CRON_TZ=Europe/Prague 0 3 * * *"). Without the prefix, the process local time zone is used.
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	var location *time.Location
+	for _, prefix := range timeZonePrefixes {
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(spec, prefix), " ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid schedule %q: missing schedule after time zone", spec)
+		}
+		loc, err := time.LoadLocation(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: unknown time zone %q: %v", spec, parts[0], err)
+		}
+		location = loc
+		spec = strings.TrimSpace(parts[1])
+		break
+	}
+
+	var (
+		schedule cron.Schedule
+		err      error
+	)
+	switch fields := strings.Fields(spec); {
+	case strings.HasPrefix(spec, "@"), len(fields) == 5:
+		schedule, err = cron.ParseStandard(spec)
+	case len(fields) == 6:
+		schedule, err = secondsScheduleParser.Parse(spec)
+	default:
+		err = fmt.Errorf("expected 5 or 6 (with seconds) fields, found %d", len(fields))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+
+	// the time zone does not change the constant delay schedule (eg. "@every 1h"), it is not wrapped so the controller
+	// can recognize it
+	if _, constantDelay := schedule.(cron.ConstantDelaySchedule); location != nil && !constantDelay {
+		return &timeZoneSchedule{location: location, schedule: schedule}, nil
+	}
+	return schedule, nil
+}
+
+// timeZoneSchedule calculates the next activation time in the given time zone.
+type timeZoneSchedule struct {
+	location *time.Location
+	schedule cron.Schedule
+}
+
+func (s *timeZoneSchedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t.In(s.location))
+}
@@ -0,0 +1,243 @@
+package factory
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// toleratedError is the configuration error that ToController() tolerates, because ToController() created the controller
+// with such configuration before the configuration was validated. Build() rejects it as any other configuration error.
+type toleratedError struct {
+	error
+}
+
+func (e toleratedError) Unwrap() error {
+	return e.error
+}
+
+// tolerated marks the configuration error as tolerated by ToController().
+func tolerated(err error) error {
+	return toleratedError{error: err}
+}
+
+// validate returns all configuration errors of the factory, so they can be reported at once.
+func (f *Factory) validate(name string) []error {
+	var errs []error
+	if len(name) == 0 {
+		errs = append(errs, tolerated(fmt.Errorf("controller name must not be empty")))
+	}
+	if f.cacheSyncTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("WithCacheSyncTimeout() timeout must be positive, got %s", f.cacheSyncTimeout))
+	}
+
+	switch {
+	case f.sync == nil && f.batchSync == nil:
+		errs = append(errs, fmt.Errorf("WithSync() or WithBatchSync() must be used"))
+	case f.sync != nil && f.batchSync != nil:
+		errs = append(errs, fmt.Errorf("WithSync() and WithBatchSync() are mutually exclusive"))
+	}
+	if f.batchSync != nil {
+		if f.batchMaxSize < 1 {
+			errs = append(errs, fmt.Errorf("WithBatchSync() max batch size must be positive, got %d", f.batchMaxSize))
+		}
+		if f.batchMaxLatency <= 0 {
+			errs = append(errs, fmt.Errorf("WithBatchSync() max latency must be positive, got %s", f.batchMaxLatency))
+		}
+	}
+
+	if f.queueKinds.Len() > 1 {
+		errs = append(errs, fmt.Errorf("%s are mutually exclusive", strings.Join(sets.List(f.queueKinds), " and ")))
+	}
+	if f.syncContext != nil && f.queueKinds.Len() > 0 {
+		errs = append(errs, fmt.Errorf("%s cannot be used with WithSyncContext(), the queue of the sync context is used", strings.Join(sets.List(f.queueKinds), " and ")))
+	}
+
+	if f.resyncInterval < 0 {
+		errs = append(errs, tolerated(fmt.Errorf("ResyncEvery() interval must not be negative, got %s", f.resyncInterval)))
+	}
+	if f.resyncOptions.jitterFactor < 0 {
+		errs = append(errs, fmt.Errorf("ResyncJitter() factor must not be negative, got %v", f.resyncOptions.jitterFactor))
+	}
+	if f.resyncOptions.initialDelay < 0 {
+		errs = append(errs, fmt.Errorf("ResyncInitialDelay() must not be negative, got %s", f.resyncOptions.initialDelay))
+	}
+	if f.resyncInterval == 0 && (f.resyncOptions != resyncOptions{}) {
+		errs = append(errs, fmt.Errorf("resync options require ResyncEvery() with positive interval"))
+	}
+	scheduleNames := sets.New[string]()
+	for i, schedule := range f.resyncSchedules {
+		if _, err := ParseSchedule(schedule.spec); err != nil {
+			// the error names the invalid schedule
+			errs = append(errs, err)
+		}
+		name := schedule.name
+		if len(name) == 0 {
+			name = fmt.Sprintf("schedule-%d", i)
+		}
+		if scheduleNames.Has(name) {
+			errs = append(errs, fmt.Errorf("schedule name %q is used by more than one schedule", name))
+		}
+		scheduleNames.Insert(name)
+	}
+
+	if policy := f.workerAutoscalingPolicy; policy != nil {
+		if policy.MinWorkers < 0 || policy.MaxWorkers < 0 {
+			errs = append(errs, fmt.Errorf("WithWorkerAutoscaling() number of workers must not be negative"))
+		}
+		if policy.MaxWorkers > 0 && policy.MinWorkers > policy.MaxWorkers {
+			errs = append(errs, fmt.Errorf("WithWorkerAutoscaling() min workers %d is greater than max workers %d", policy.MinWorkers, policy.MaxWorkers))
+		}
+		if policy.Interval < 0 {
+			errs = append(errs, fmt.Errorf("WithWorkerAutoscaling() interval must not be negative, got %s", policy.Interval))
+		}
+	}
+
+	return append(errs, f.validateInformers()...)
+}
+
+// validateInformers checks that no informer is nil and that no informer is registered twice the same way, which would add
+// every key to the queue twice. The informers registered twice via the methods that ToController() accepted before are
+// reported as tolerated errors (see toleratedError).
+func (f *Factory) validateInformers() []error {
+	var errs []error
+	check := func(method string, seen map[framework.Informer]bool, toleratedTwice bool, informers ...framework.Informer) {
+		for _, informer := range informers {
+			switch {
+			case informer == nil:
+				errs = append(errs, fmt.Errorf("%s informer must not be nil", method))
+			case seen[informer]:
+				err := fmt.Errorf("%s informer %T registered more than once", method, informer)
+				if toleratedTwice {
+					err = tolerated(err)
+				}
+				errs = append(errs, err)
+			default:
+				seen[informer] = true
+			}
+		}
+	}
+
+	// the informers registered with the default queue keys function
+	defaultKeys := map[framework.Informer]bool{}
+	for _, i := range f.informers {
+		check("WithInformers()", defaultKeys, true, i.informers...)
+	}
+	for _, i := range f.namespaceInformers {
+		check("WithNamespaceInformer()", defaultKeys, true, i.informer)
+	}
+	for _, i := range f.informerQueueKeys {
+		if i.queueKeyFn == nil {
+			errs = append(errs, tolerated(fmt.Errorf("WithInformersQueueKeysFunc() queue keys function must not be nil")))
+		}
+		check("WithInformersQueueKeysFunc()", map[framework.Informer]bool{}, true, i.informers...)
+	}
+
+	// the informers with the event handlers added by the factory must not be registered as bare informers
+	withHandlers := map[framework.Informer]bool{}
+	for informer := range defaultKeys {
+		withHandlers[informer] = true
+	}
+	for _, i := range f.informerQueueKeys {
+		for _, informer := range i.informers {
+			withHandlers[informer] = true
+		}
+	}
+	for _, r := range f.indexedReferences {
+		for _, informer := range r.referenced {
+			withHandlers[informer] = true
+		}
+	}
+	bare := map[framework.Informer]bool{}
+	check("WithBareInformers()", bare, true, f.bareInformers...)
+	for informer := range bare {
+		if withHandlers[informer] {
+			errs = append(errs, tolerated(fmt.Errorf("WithBareInformers() informer %T must not be registered with event handlers", informer)))
+		}
+	}
+
+	for _, d := range f.debouncedInformers {
+		if d.window <= 0 {
+			errs = append(errs, fmt.Errorf("WithInformerDebounce() window must be positive, got %s", d.window))
+		}
+		if d.maxWait < 0 {
+			errs = append(errs, fmt.Errorf("WithInformerDebounce() max wait must not be negative, got %s", d.maxWait))
+		}
+		for _, informer := range d.informers {
+			if informer == nil {
+				errs = append(errs, fmt.Errorf("WithInformerDebounce() informer must not be nil"))
+				continue
+			}
+			if !withHandlers[informer] {
+				errs = append(errs, fmt.Errorf("WithInformerDebounce() informer %T must be registered via WithInformers*() methods", informer))
+			}
+		}
+	}
+
+	indexes := map[framework.Informer]map[string]*Index{}
+	for _, r := range f.indexedReferences {
+		switch {
+		case r.index == nil:
+			errs = append(errs, fmt.Errorf("WithIndexedReferences() index must not be nil"))
+		case len(r.index.name) == 0:
+			errs = append(errs, fmt.Errorf("WithIndexedReferences() index name must not be empty"))
+		case r.index.indexFunc == nil:
+			errs = append(errs, fmt.Errorf("WithIndexedReferences() index function must not be nil"))
+		}
+		if r.primary == nil {
+			errs = append(errs, fmt.Errorf("WithIndexedReferences() primary informer must not be nil"))
+		} else if informer, ok := r.primary.(indexedInformer); !ok {
+			errs = append(errs, fmt.Errorf("WithIndexedReferences() informer %T does not support indexing", r.primary))
+		} else if r.index != nil {
+			// the index can be registered by another controller or by another WithIndexedReferences() of this controller
+			if registered, exists := indexes[r.primary][r.index.name]; exists && registered != r.index {
+				errs = append(errs, fmt.Errorf("WithIndexedReferences() index %q of informer %T is already registered with a different index", r.index.name, r.primary))
+			} else {
+				registeredIndexes.lock.Lock()
+				err := conflictingIndex(informer.GetIndexer(), r.index)
+				registeredIndexes.lock.Unlock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("WithIndexedReferences() index of informer %T is not valid: %v", r.primary, err))
+				}
+			}
+			if indexes[r.primary] == nil {
+				indexes[r.primary] = map[string]*Index{}
+			}
+			indexes[r.primary][r.index.name] = r.index
+		}
+		check("WithIndexedReferences()", map[framework.Informer]bool{}, false, r.referenced...)
+	}
+
+	namespaceSelections := map[framework.Informer]bool{}
+	for _, s := range f.namespaceSelections {
+		if s.selection == nil {
+			errs = append(errs, fmt.Errorf("WithNamespaceSelection() selection must not be nil"))
+		} else if controller := s.selection.registeredController(); len(controller) > 0 {
+			errs = append(errs, fmt.Errorf("WithNamespaceSelection() selection is already used by %q controller", controller))
+		}
+		check("WithNamespaceSelection()", namespaceSelections, false, s.informer)
+	}
+
+	if len(f.resourceInformers) > 0 && f.sharedInformers == nil {
+		errs = append(errs, fmt.Errorf("WithSharedInformers() must be used with WithResourceInformers()"))
+	}
+	for _, resource := range f.resourceInformers {
+		if len(resource.Resource.Resource) == 0 || len(resource.Resource.Version) == 0 {
+			errs = append(errs, fmt.Errorf("WithResourceInformers() resource %q must have version and resource", resource.Resource.String()))
+		}
+		if f.sharedInformers == nil {
+			continue
+		}
+		// checked upfront, so no informer is created by SharedInformers.ForResource() when the configuration is invalid
+		switch {
+		case resource.MetadataOnly && f.sharedInformers.metadataClient == nil:
+			errs = append(errs, fmt.Errorf("WithResourceInformers() metadata client is required for metadata only informer of %s", resource.Resource.String()))
+		case !resource.MetadataOnly && f.sharedInformers.dynamicClient == nil:
+			errs = append(errs, fmt.Errorf("WithResourceInformers() dynamic client is required for informer of %s", resource.Resource.String()))
+		}
+	}
+	return errs
+}
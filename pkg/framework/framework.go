@@ -2,6 +2,8 @@ package framework
 
 import (
 	"context"
+	"time"
+
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/util/workqueue"
 
@@ -26,6 +28,44 @@ type Controller interface {
 	Name() string
 }
 
+type returnOnCacheSyncTimeoutContextKey struct{}
+
+// WithReturnOnCacheSyncTimeout returns a copy of the context that makes the controller Run() return when the informers
+// do not sync in time, instead of exiting the process. This is used for controllers that are restarted when they stop
+// (eg. the conditional controllers of the manager).
+func WithReturnOnCacheSyncTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, returnOnCacheSyncTimeoutContextKey{}, true)
+}
+
+// ReturnOnCacheSyncTimeout returns true when the controller Run() should return when the informers do not sync in time.
+func ReturnOnCacheSyncTimeout(ctx context.Context) bool {
+	returnOnTimeout, _ := ctx.Value(returnOnCacheSyncTimeoutContextKey{}).(bool)
+	return returnOnTimeout
+}
+
+// PeriodicResyncer is implemented by controllers that periodically resync.
+// This allows controller manager to spread the resyncs of multiple controllers across the resync interval.
+type PeriodicResyncer interface {
+	// ResyncInterval returns the periodic resync interval or zero when the periodic resync is not enabled.
+	ResyncInterval() time.Duration
+
+	// SetResyncInitialDelay sets the delay of the first periodic resync. This must be called before Run().
+	SetResyncInitialDelay(delay time.Duration)
+}
+
+// Pausable is implemented by controllers that can be paused at runtime without stopping them.
+// Paused controller keeps its informers and queue running, but the workers stop processing the queue until the controller is resumed.
+type Pausable interface {
+	// Pause stops the workers from processing the queue.
+	Pause()
+
+	// Resume resumes the processing of the queue.
+	Resume()
+
+	// Paused returns true if the controller is paused.
+	Paused() bool
+}
+
 // Context interface represents a context given to the Sync() function where the main controller logic happen.
 // Context exposes controller name and give user access to the queue (for manual requeue).
 // Context also provides metadata about object that informers observed as changed.
@@ -54,6 +94,11 @@ type ControllerSyncErrorFn func(error) error
 // The syncContext provides access to controller name, queue and event recorder.
 type ControllerSyncFn func(ctx context.Context, controllerContext Context) error
 
+// ControllerBatchSyncFn is a function that contain main controller logic for controllers that reconcile multiple keys at once.
+// The keys are the queue keys collected for this batch. The returned map holds the errors of keys that failed to sync,
+// the failed keys are requeued with rate limiting. The keys not present in the map are considered successfully synced.
+type ControllerBatchSyncFn func(ctx context.Context, controllerContext Context, keys []string) map[string]error
+
 // PostStartHook specify a function that will run after controller is started.
 // The context is cancelled when the controller is asked to shutdown and the post start hook should terminate as well.
 // The syncContext allow access to controller queue and event recorder.
@@ -1,6 +1,15 @@
 package framework
 
-import "k8s.io/client-go/tools/cache"
+import (
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
 
 // Informer represents any structure that allow to register event handlers and informs if caches are synced.
 // Any SharedInformer will comply.
@@ -8,3 +17,97 @@ type Informer interface {
 	AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error)
 	HasSynced() bool
 }
+
+// ListerProvider is implemented by the sync contexts of controllers with informers created by the controller factory
+// (see factory.WithResourceInformers). It gives the sync function access to the listers of these informers.
+type ListerProvider interface {
+	// Lister returns the lister of the given resource or false if the controller does not have informer for the resource.
+	Lister(resource schema.GroupVersionResource) (cache.GenericLister, bool)
+}
+
+// ListerFor returns the lister of the resource from the sync context.
+func ListerFor(syncContext Context, resource schema.GroupVersionResource) (cache.GenericLister, error) {
+	provider, ok := syncContext.(ListerProvider)
+	if !ok {
+		return nil, fmt.Errorf("sync context %T does not provide listers", syncContext)
+	}
+	lister, ok := provider.Lister(resource)
+	if !ok {
+		return nil, fmt.Errorf("no informer for %s", resource.String())
+	}
+	return lister, nil
+}
+
+// TypedLister lists the objects of the generic lister converted to the type T (eg. *corev1.Secret or *metav1.PartialObjectMetadata).
+// The informers created by the controller factory cache the unstructured objects (or the partial object metadata), the
+// unstructured objects are converted on every call, so prefer the generic lister in hot paths.
+type TypedLister[T runtime.Object] struct {
+	lister cache.GenericLister
+}
+
+// NewTypedLister returns the lister converting the objects of the generic lister to the type T.
+func NewTypedLister[T runtime.Object](lister cache.GenericLister) TypedLister[T] {
+	return TypedLister[T]{lister: lister}
+}
+
+// TypedListerFor returns the lister of the resource from the sync context converting the objects to the type T.
+func TypedListerFor[T runtime.Object](syncContext Context, resource schema.GroupVersionResource) (TypedLister[T], error) {
+	lister, err := ListerFor(syncContext, resource)
+	if err != nil {
+		return TypedLister[T]{}, err
+	}
+	return NewTypedLister[T](lister), nil
+}
+
+// List lists all objects matching the selector.
+func (l TypedLister[T]) List(selector labels.Selector) ([]T, error) {
+	objs, err := l.lister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]T, 0, len(objs))
+	for _, obj := range objs {
+		typed, err := convertTo[T](obj)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, typed)
+	}
+	return result, nil
+}
+
+// Get returns the object with the given namespace and name, the namespace is empty for cluster scoped objects.
+func (l TypedLister[T]) Get(namespace, name string) (T, error) {
+	var obj runtime.Object
+	var err error
+	if len(namespace) == 0 {
+		obj, err = l.lister.Get(name)
+	} else {
+		obj, err = l.lister.ByNamespace(namespace).Get(name)
+	}
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return convertTo[T](obj)
+}
+
+func convertTo[T runtime.Object](obj runtime.Object) (T, error) {
+	var zero T
+	if typed, ok := obj.(T); ok {
+		return typed, nil
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return zero, fmt.Errorf("unable to convert %T to %T", obj, zero)
+	}
+	objType := reflect.TypeOf(zero)
+	if objType == nil || objType.Kind() != reflect.Pointer {
+		return zero, fmt.Errorf("unable to convert %T to %T, pointer type is required", obj, zero)
+	}
+	typed := reflect.New(objType.Elem()).Interface().(T)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), typed); err != nil {
+		return zero, fmt.Errorf("unable to convert %T to %T: %v", obj, zero, err)
+	}
+	return typed, nil
+}
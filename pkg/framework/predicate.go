@@ -0,0 +1,53 @@
+package framework
+
+// Predicate decides whether the informer event should trigger the Sync() call.
+// Unlike the EventFilterFunc, the predicate receives both the old and the new object on update, so it can react only on
+// specific changes (eg. when the generation increased). See the predicates package for the stock predicates.
+// On delete, the object can be cache.DeletedFinalStateUnknown tombstone.
+// The EventFilterFunc implements the Predicate, so the filters can be passed wherever the predicate is accepted.
+type Predicate interface {
+	// Create returns true if the added object should trigger the sync.
+	Create(obj interface{}) bool
+	// Update returns true if the update of the object should trigger the sync.
+	Update(oldObj, newObj interface{}) bool
+	// Delete returns true if the deleted object should trigger the sync.
+	Delete(obj interface{}) bool
+}
+
+// PredicateFuncs implements Predicate using the functions. The events with nil function are accepted.
+type PredicateFuncs struct {
+	CreateFunc func(obj interface{}) bool
+	UpdateFunc func(oldObj, newObj interface{}) bool
+	DeleteFunc func(obj interface{}) bool
+}
+
+var _ Predicate = PredicateFuncs{}
+
+func (p PredicateFuncs) Create(obj interface{}) bool {
+	return p.CreateFunc == nil || p.CreateFunc(obj)
+}
+
+func (p PredicateFuncs) Update(oldObj, newObj interface{}) bool {
+	return p.UpdateFunc == nil || p.UpdateFunc(oldObj, newObj)
+}
+
+func (p PredicateFuncs) Delete(obj interface{}) bool {
+	return p.DeleteFunc == nil || p.DeleteFunc(obj)
+}
+
+var _ Predicate = EventFilterFunc(nil)
+
+// Create implements Predicate, the filter is called with the added object.
+func (f EventFilterFunc) Create(obj interface{}) bool {
+	return f == nil || f(obj)
+}
+
+// Update implements Predicate, the update is accepted when the filter accepts the old or the new object.
+func (f EventFilterFunc) Update(oldObj, newObj interface{}) bool {
+	return f == nil || f(oldObj) || f(newObj)
+}
+
+// Delete implements Predicate, the filter is called with the deleted object.
+func (f EventFilterFunc) Delete(obj interface{}) bool {
+	return f == nil || f(obj)
+}
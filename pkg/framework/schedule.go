@@ -0,0 +1,76 @@
+package framework
+
+import (
+	"context"
+	"time"
+)
+
+// ScheduledRun describes the run of a controller triggered by a resync schedule.
+type ScheduledRun struct {
+	// Name is the name of the schedule that fired. For schedules registered via factory, this is the name set by
+	// factory.ScheduleName or "schedule-<index>" when the name is not set.
+	Name string
+
+	// PlannedTime is the time the schedule was planned to fire at.
+	PlannedTime time.Time
+
+	// CatchUp is true when the run was missed (eg. the process was down when the schedule should fire) and is run late
+	// because of the MissedRunPolicy.
+	CatchUp bool
+}
+
+// MissedRunPolicy determines what happens with the schedule runs that were missed because the process was down or
+// the previous run of the schedule was not processed yet.
+type MissedRunPolicy string
+
+const (
+	// MissedRunSkip skips the missed runs, the schedule continues with the next planned run. This is the default.
+	MissedRunSkip MissedRunPolicy = "Skip"
+	// MissedRunOnce runs the schedule once on startup when a run was missed since the last recorded run.
+	MissedRunOnce MissedRunPolicy = "RunOnce"
+	// MissedRunAll runs every missed run, one after another. The runs are never collapsed, next run is not queued before
+	// the previous one was picked by a worker.
+	MissedRunAll MissedRunPolicy = "RunAll"
+)
+
+// ScheduleStore persists the time of the last run of the schedules, so the missed runs can be detected after restart.
+type ScheduleStore interface {
+	// LastRun returns the planned time of the last run of the controller schedule. Zero time is returned when no run was recorded.
+	LastRun(ctx context.Context, controllerName, scheduleName string) (time.Time, error)
+
+	// SetLastRun records the planned time of the last run of the controller schedule.
+	SetLastRun(ctx context.Context, controllerName, scheduleName string, lastRun time.Time) error
+}
+
+// ScheduleStatus describes the state of the controller resync schedule.
+type ScheduleStatus struct {
+	Name            string          `json:"name"`
+	MissedRunPolicy MissedRunPolicy `json:"missedRunPolicy"`
+	// LastRun is the planned time of the last run, zero if the schedule did not fire yet.
+	LastRun time.Time `json:"lastRun"`
+	// NextRun is the planned time of the next run, zero if the controller is not running.
+	NextRun time.Time `json:"nextRun"`
+}
+
+// ScheduleInspector is implemented by controllers that run resync schedules.
+type ScheduleInspector interface {
+	// Schedules returns the status of all controller resync schedules.
+	Schedules() []ScheduleStatus
+}
+
+// ScheduleQueueKeyFunc returns the queue key that is added to the controller queue when the schedule fires.
+type ScheduleQueueKeyFunc func(run ScheduledRun) string
+
+type scheduledRunContextKey struct{}
+
+// WithScheduledRun returns a copy of the context carrying the scheduled run.
+func WithScheduledRun(ctx context.Context, run ScheduledRun) context.Context {
+	return context.WithValue(ctx, scheduledRunContextKey{}, run)
+}
+
+// ScheduledRunFromContext returns the scheduled run that triggered the Sync() call.
+// The second value is false when the Sync() was not triggered by a resync schedule.
+func ScheduledRunFromContext(ctx context.Context) (ScheduledRun, bool) {
+	run, ok := ctx.Value(scheduledRunContextKey{}).(ScheduledRun)
+	return run, ok
+}
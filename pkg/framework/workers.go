@@ -0,0 +1,29 @@
+package framework
+
+import "time"
+
+// WorkerScaler is implemented by controllers that allow to change the number of workers while the controller is running.
+type WorkerScaler interface {
+	// SetWorkers changes the number of workers. When scaling down, the workers terminate after finishing their current item.
+	SetWorkers(workers int)
+
+	// Workers returns the current number of workers.
+	Workers() int
+}
+
+// WorkerAutoscalingPolicy describes how the number of controller workers is adjusted based on the queue depth and sync latency.
+// Every Interval, the number of workers is set to the number needed to keep at most QueueDepthPerWorker keys in queue per worker.
+// When the average sync latency exceeds TargetSyncLatency and there are keys waiting in the queue, one more worker is added.
+// The workers are removed one at a time when the queue is empty.
+type WorkerAutoscalingPolicy struct {
+	MinWorkers int
+	MaxWorkers int
+
+	// QueueDepthPerWorker is the number of queued keys per worker that triggers scale up. Zero disables the queue depth scaling.
+	QueueDepthPerWorker int
+	// TargetSyncLatency is the average sync duration that triggers scale up. Zero disables the latency scaling.
+	TargetSyncLatency time.Duration
+
+	// Interval is how often the number of workers is evaluated. Defaults to 30 seconds.
+	Interval time.Duration
+}
@@ -0,0 +1,189 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// ControllerFunc returns new instance of the controller. The conditional controllers are created again every time the
+// required APIs become available, because the stopped controller (and its queue) cannot be started again.
+type ControllerFunc func() (framework.Controller, error)
+
+// conditionalController is the controller that runs only when all required resources are served by the API server.
+type conditionalController struct {
+	name          string
+	newController ControllerFunc
+	workers       int
+	resources     []schema.GroupVersionResource
+
+	// controller, cancel and done are set while the controller runs, they are cleared when the controller Run() returns
+	controller framework.Controller
+	cancel     context.CancelFunc
+	done       chan struct{}
+	// stopping is set when the controller context was cancelled and the controller did not return yet
+	stopping bool
+}
+
+// running returns true until the controller Run() returns. The manager lock must be held.
+func (c *conditionalController) running() bool {
+	return c.done != nil
+}
+
+// WithDiscovery sets the discovery client used to check the availability of resources required by the conditional
+// controllers (see WithConditionalController). The discovery is polled every interval, so the controllers are started
+// when the CRD serving the resources is installed and stopped when it is removed.
+func (m *Manager) WithDiscovery(client discovery.DiscoveryInterface, interval time.Duration) *Manager {
+	m.discoveryClient = client
+	m.discoveryInterval = interval
+	return m
+}
+
+// WithConditionalController registers the controller that runs only while all the resources are served by the API server.
+// The controller is created by newController when the resources become available and it is stopped when any of them
+// is removed. The controllers should create their informers via factory.WithResourceInformers(), so the informers are
+// started together with the controller and stopped when the last controller using them stops.
+// The name is used to report the controller status while it is not running, it should match the controller name.
+func (m *Manager) WithConditionalController(name string, newController ControllerFunc, workers int, resources ...schema.GroupVersionResource) *Manager {
+	m.conditionalControllers = append(m.conditionalControllers, &conditionalController{
+		name:          name,
+		newController: newController,
+		workers:       workers,
+		resources:     resources,
+	})
+	return m
+}
+
+// availableResources returns the required resources of conditional controllers that are served by the API server.
+// The group versions that failed to be discovered are returned as unknown, so the controllers using them are left as they are.
+func (m *Manager) availableResources() (available, unknown sets.Set[schema.GroupVersionResource]) {
+	available, unknown = sets.New[schema.GroupVersionResource](), sets.New[schema.GroupVersionResource]()
+	if cached, ok := m.discoveryClient.(discovery.CachedDiscoveryInterface); ok {
+		cached.Invalidate()
+	}
+	groupVersions := map[schema.GroupVersion][]schema.GroupVersionResource{}
+	for _, c := range m.conditionalControllers {
+		for _, resource := range c.resources {
+			groupVersions[resource.GroupVersion()] = append(groupVersions[resource.GroupVersion()], resource)
+		}
+	}
+	for groupVersion, resources := range groupVersions {
+		resourceList, err := m.discoveryClient.ServerResourcesForGroupVersion(groupVersion.String())
+		switch {
+		case errors.IsNotFound(err):
+			continue
+		case err != nil:
+			utilruntime.HandleError(fmt.Errorf("failed to discover resources of %s: %v", groupVersion.String(), err))
+			unknown.Insert(resources...)
+			continue
+		}
+		served := sets.New[string]()
+		for _, resource := range resourceList.APIResources {
+			served.Insert(resource.Name)
+		}
+		for _, resource := range resources {
+			if served.Has(resource.Resource) {
+				available.Insert(resource)
+			}
+		}
+	}
+	return available, unknown
+}
+
+// syncConditionalControllers starts the conditional controllers with all resources available and stops the running
+// controllers with any resource removed.
+func (m *Manager) syncConditionalControllers(ctx context.Context) {
+	available, unknown := m.availableResources()
+	for _, c := range m.conditionalControllers {
+		required := sets.New[schema.GroupVersionResource](c.resources...)
+		if required.Intersection(unknown).Len() > 0 {
+			continue
+		}
+		m.lock.Lock()
+		running, stopping := c.running(), c.stopping
+		m.lock.Unlock()
+		switch isAvailable := available.IsSuperset(required); {
+		case isAvailable && !running:
+			m.startConditionalController(ctx, c)
+		case !isAvailable && running && !stopping:
+			klog.Infof("Stopping controller %s, the required resources are no longer available", c.name)
+			m.stopConditionalController(c)
+		}
+	}
+}
+
+func (m *Manager) startConditionalController(ctx context.Context, c *conditionalController) {
+	controller, err := c.newController()
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to create controller %s: %v", c.name, err))
+		return
+	}
+	m.lock.Lock()
+	if pausable, ok := controller.(framework.Pausable); ok && m.pausedNames.Has(controller.Name()) {
+		pausable.Pause()
+	}
+	// the cache sync timeout stops only this controller, it is started again by the next discovery poll
+	controllerCtx, cancel := context.WithCancel(framework.WithReturnOnCacheSyncTimeout(ctx))
+	done := make(chan struct{})
+	c.controller, c.cancel, c.done, c.stopping = controller, cancel, done, false
+	m.lock.Unlock()
+
+	klog.Infof("Starting controller %s with %d workers, the required resources are available", c.name, c.workers)
+	go func() {
+		defer close(done)
+		controller.Run(controllerCtx, c.workers)
+		cancel()
+
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		if c.done != done {
+			return
+		}
+		if !c.stopping && ctx.Err() == nil {
+			klog.Warningf("Controller %s stopped unexpectedly, it will be started again", c.name)
+		}
+		c.controller, c.cancel, c.done, c.stopping = nil, nil, nil, false
+	}()
+}
+
+// stopConditionalController cancels the controller context. It does not wait for the controller to finish, so a slow
+// controller does not block the other conditional controllers. The controller is reported as running until it returns.
+func (m *Manager) stopConditionalController(c *conditionalController) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	c.stopping = true
+	c.cancel()
+}
+
+// runConditionalControllers polls the discovery and starts or stops the conditional controllers until the context is cancelled.
+// All running conditional controllers are stopped before this returns.
+func (m *Manager) runConditionalControllers(ctx context.Context) {
+	if m.discoveryClient == nil {
+		klog.Errorf("Discovery client is not set, the conditional controllers will not be started")
+		return
+	}
+	wait.UntilWithContext(ctx, m.syncConditionalControllers, m.discoveryInterval)
+
+	// the controller contexts are cancelled together with the manager context, all controllers stop in parallel
+	var running []chan struct{}
+	m.lock.Lock()
+	for _, c := range m.conditionalControllers {
+		if c.running() {
+			running = append(running, c.done)
+		}
+	}
+	m.lock.Unlock()
+	for _, done := range running {
+		<-done
+	}
+}
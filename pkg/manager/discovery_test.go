@@ -0,0 +1,248 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// lockedDiscovery guards the resources of the fake discovery, so the test can change them while the manager polls.
+type lockedDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	lock sync.Mutex
+}
+
+func (d *lockedDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.FakeDiscovery.ServerResourcesForGroupVersion(groupVersion)
+}
+
+func (d *lockedDiscovery) setResources(resources ...*metav1.APIResourceList) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.Resources = resources
+}
+
+type stoppableController struct {
+	fakeController
+	stopped chan struct{}
+}
+
+func (c *stoppableController) Run(ctx context.Context, workers int) {
+	c.fakeController.Run(ctx, workers)
+	close(c.stopped)
+}
+
+func TestManager_ConditionalControllers(t *testing.T) {
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	gadgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"}
+	client := &lockedDiscovery{FakeDiscovery: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}}
+
+	started := make(chan *stoppableController, 10)
+	m := New().WithDiscovery(client, 10*time.Millisecond).
+		WithController(&fakeController{name: "static"}, 1).
+		WithConditionalController("widgets", func() (framework.Controller, error) {
+			c := &stoppableController{fakeController: fakeController{name: "widgets"}, stopped: make(chan struct{})}
+			started <- c
+			return c, nil
+		}, 1, widgets, gadgets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+
+	inactive := func() bool {
+		for _, status := range m.Status() {
+			if status.Name == "widgets" {
+				return status.Inactive
+			}
+		}
+		t.Fatalf("expected widgets controller status")
+		return false
+	}
+	expectStarted := func() *stoppableController {
+		t.Helper()
+		select {
+		case c := <-started:
+			return c
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected controller to be started")
+			return nil
+		}
+	}
+	expectStopped := func(c *stoppableController) {
+		t.Helper()
+		select {
+		case <-c.stopped:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected controller to be stopped")
+		}
+	}
+
+	// only one of the required resources is available
+	client.setResources(&metav1.APIResourceList{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{{Name: "widgets"}}})
+	time.Sleep(50 * time.Millisecond)
+	if len(started) > 0 || !inactive() {
+		t.Fatalf("expected controller not to start without all required resources")
+	}
+
+	client.setResources(&metav1.APIResourceList{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{{Name: "widgets"}, {Name: "gadgets"}}})
+	first := expectStarted()
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) { return !inactive(), nil }); err != nil {
+		t.Fatalf("expected controller to be reported as active")
+	}
+
+	// the CRD is removed
+	client.setResources()
+	expectStopped(first)
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) { return inactive(), nil }); err != nil {
+		t.Fatalf("expected controller to be reported as inactive")
+	}
+
+	// the CRD is installed again, new controller instance is started
+	client.setResources(&metav1.APIResourceList{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{{Name: "widgets"}, {Name: "gadgets"}}})
+	second := expectStarted()
+	if second == first {
+		t.Fatalf("expected new controller instance")
+	}
+
+	cancel()
+	expectStopped(second)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected manager to stop")
+	}
+}
+
+// returningController returns from Run() immediately, without its context being cancelled.
+type returningController struct {
+	fakeController
+	returned chan struct{}
+}
+
+func (c *returningController) Run(ctx context.Context, workers int) {
+	defer close(c.returned)
+}
+
+// slowController does not return from Run() until it is released after the context is cancelled.
+type slowController struct {
+	fakeController
+	release chan struct{}
+	stopped chan struct{}
+}
+
+func (c *slowController) Run(ctx context.Context, workers int) {
+	defer close(c.stopped)
+	<-ctx.Done()
+	<-c.release
+}
+
+func TestManager_ConditionalControllerRestartedWhenReturned(t *testing.T) {
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	client := &lockedDiscovery{FakeDiscovery: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}}
+	client.setResources(&metav1.APIResourceList{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{{Name: "widgets"}}})
+
+	started := make(chan *returningController, 10)
+	m := New().WithDiscovery(client, 10*time.Millisecond).
+		WithConditionalController("widgets", func() (framework.Controller, error) {
+			c := &returningController{fakeController: fakeController{name: "widgets"}, returned: make(chan struct{})}
+			started <- c
+			return c, nil
+		}, 1, widgets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected controller to be started again after it returned")
+		}
+	}
+}
+
+func TestManager_SlowConditionalControllerDoesNotBlockOthers(t *testing.T) {
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	gadgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"}
+	client := &lockedDiscovery{FakeDiscovery: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}}
+	client.setResources(&metav1.APIResourceList{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{{Name: "widgets"}}})
+
+	slow := &slowController{fakeController: fakeController{name: "widgets"}, release: make(chan struct{}), stopped: make(chan struct{})}
+	gadgetsStarted := make(chan struct{})
+	m := New().WithDiscovery(client, 10*time.Millisecond).
+		WithConditionalController("widgets", func() (framework.Controller, error) {
+			return slow, nil
+		}, 1, widgets).
+		WithConditionalController("gadgets", func() (framework.Controller, error) {
+			close(gadgetsStarted)
+			return &fakeController{name: "gadgets"}, nil
+		}, 1, gadgets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		for _, status := range m.Status() {
+			if status.Name == "widgets" {
+				return !status.Inactive, nil
+			}
+		}
+		return false, nil
+	}); err != nil {
+		t.Fatalf("expected widgets controller to be started")
+	}
+
+	// the widgets controller is stopping, but it does not return until it is released
+	client.setResources(&metav1.APIResourceList{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{{Name: "gadgets"}}})
+	select {
+	case <-gadgetsStarted:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected gadgets controller to be started while widgets controller is stopping")
+	}
+
+	// the manager waits for the slow controller before it returns
+	cancel()
+	select {
+	case <-done:
+		t.Fatalf("expected manager to wait for the stopping controller")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(slow.release)
+	select {
+	case <-slow.stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected widgets controller to be stopped")
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected manager to stop")
+	}
+}
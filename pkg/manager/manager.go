@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+type runnableController struct {
+	controller framework.Controller
+	workers    int
+}
+
+// Manager runs multiple controllers and coordinates the features that span across them.
+type Manager struct {
+	controllers    []runnableController
+	staggerResyncs bool
+
+	pauseTriggers []pauseTrigger
+	// pausedNames are the names of paused controllers, applied to the conditional controllers when they start
+	pausedNames sets.Set[string]
+
+	conditionalControllers []*conditionalController
+	discoveryClient        discovery.DiscoveryInterface
+	discoveryInterval      time.Duration
+
+	lock sync.Mutex
+}
+
+// New return new controller manager.
+func New() *Manager {
+	return &Manager{
+		pausedNames:       sets.New[string](),
+		discoveryInterval: 30 * time.Second,
+	}
+}
+
+// WithController registers the controller to be started by the manager with the given number of workers.
+func (m *Manager) WithController(controller framework.Controller, workers int) *Manager {
+	m.controllers = append(m.controllers, runnableController{controller: controller, workers: workers})
+	return m
+}
+
+// WithStaggeredResyncs spreads the periodic resyncs of registered controllers across their resync interval.
+// Without this, all controllers in a binary start at the same moment and their resyncs line up.
+// The controller at position i out of n controllers with periodic resync gets the initial delay of interval*i/n.
+// Only controllers implementing framework.PeriodicResyncer are staggered.
+func (m *Manager) WithStaggeredResyncs() *Manager {
+	m.staggerResyncs = true
+	return m
+}
+
+// resyncers return the registered controllers with periodic resync enabled.
+func (m *Manager) resyncers() []framework.PeriodicResyncer {
+	var result []framework.PeriodicResyncer
+	for _, c := range m.controllers {
+		resyncer, ok := c.controller.(framework.PeriodicResyncer)
+		if !ok || resyncer.ResyncInterval() <= 0 {
+			continue
+		}
+		result = append(result, resyncer)
+	}
+	return result
+}
+
+// staggerResyncPhases sets the initial resync delay of every periodic resyncer so their phases are evenly spread.
+func (m *Manager) staggerResyncPhases() {
+	resyncers := m.resyncers()
+	for i, resyncer := range resyncers {
+		delay := time.Duration(int64(resyncer.ResyncInterval()) * int64(i) / int64(len(resyncers)))
+		resyncer.SetResyncInitialDelay(delay)
+	}
+}
+
+// Schedules returns the status of resync schedules of all registered controllers, keyed by the controller name.
+// Only controllers implementing framework.ScheduleInspector and having at least one schedule are included.
+// This is intended to be exposed via debug endpoint.
+func (m *Manager) Schedules() map[string][]framework.ScheduleStatus {
+	result := map[string][]framework.ScheduleStatus{}
+	for _, c := range m.controllers {
+		inspector, ok := c.controller.(framework.ScheduleInspector)
+		if !ok {
+			continue
+		}
+		if schedules := inspector.Schedules(); len(schedules) > 0 {
+			result[c.controller.Name()] = schedules
+		}
+	}
+	return result
+}
+
+// ControllerStatus describes the state of the controller run by the manager.
+type ControllerStatus struct {
+	Name   string `json:"name"`
+	Paused bool   `json:"paused"`
+	// Inactive means the conditional controller does not run, because the resources it requires are not available
+	Inactive bool `json:"inactive,omitempty"`
+	// Schedules are the resync schedules of the controller
+	Schedules []framework.ScheduleStatus `json:"schedules,omitempty"`
+}
+
+// Status returns the status of all registered controllers, including the conditional controllers.
+func (m *Manager) Status() []ControllerStatus {
+	result := make([]ControllerStatus, 0, len(m.controllers)+len(m.conditionalControllers))
+	for _, c := range m.controllers {
+		result = append(result, controllerStatus(c.controller))
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, c := range m.conditionalControllers {
+		if c.controller == nil {
+			result = append(result, ControllerStatus{Name: c.name, Inactive: true})
+			continue
+		}
+		result = append(result, controllerStatus(c.controller))
+	}
+	return result
+}
+
+func controllerStatus(controller framework.Controller) ControllerStatus {
+	status := ControllerStatus{Name: controller.Name()}
+	if pausable, ok := controller.(framework.Pausable); ok {
+		status.Paused = pausable.Paused()
+	}
+	if inspector, ok := controller.(framework.ScheduleInspector); ok {
+		status.Schedules = inspector.Schedules()
+	}
+	return status
+}
+
+// StatusHandler returns HTTP handler serving the status of all registered controllers as JSON.
+// This is intended to be registered on the health or debug endpoint.
+func (m *Manager) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(m.Status()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Run starts all registered controllers and blocks until all of them finish.
+// The conditional controllers are started and stopped as their resources appear and disappear (see WithConditionalController).
+// Cancelling the context causes all controllers to shut down.
+func (m *Manager) Run(ctx context.Context) {
+	if m.staggerResyncs {
+		m.staggerResyncPhases()
+	}
+
+	var wg sync.WaitGroup
+	for _, trigger := range m.pauseTriggers {
+		wg.Add(1)
+		go func(trigger pauseTrigger) {
+			defer wg.Done()
+			trigger(ctx, m.setPausedControllers)
+		}(trigger)
+	}
+	if len(m.conditionalControllers) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.runConditionalControllers(ctx)
+		}()
+	}
+	for _, c := range m.controllers {
+		wg.Add(1)
+		go func(c runnableController) {
+			defer wg.Done()
+			klog.V(4).Infof("Starting controller %s with %d workers", c.controller.Name(), c.workers)
+			c.controller.Run(ctx, c.workers)
+		}(c)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,209 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+type fakeController struct {
+	name         string
+	interval     time.Duration
+	initialDelay time.Duration
+	ran          bool
+	sync.Mutex
+}
+
+func (f *fakeController) Run(ctx context.Context, workers int) {
+	f.Lock()
+	f.ran = true
+	f.Unlock()
+	<-ctx.Done()
+}
+
+func (f *fakeController) Sync(ctx context.Context, controllerContext framework.Context) error {
+	return nil
+}
+
+func (f *fakeController) Name() string {
+	return f.name
+}
+
+func (f *fakeController) ResyncInterval() time.Duration {
+	return f.interval
+}
+
+func (f *fakeController) SetResyncInitialDelay(delay time.Duration) {
+	f.initialDelay = delay
+}
+
+func TestManager_StaggeredResyncs(t *testing.T) {
+	controllers := []*fakeController{
+		{name: "a", interval: time.Minute},
+		{name: "b", interval: 2 * time.Minute},
+		{name: "no-resync"},
+		{name: "c", interval: time.Minute},
+		{name: "d", interval: time.Minute},
+	}
+	m := New().WithStaggeredResyncs()
+	for _, c := range controllers {
+		m.WithController(c, 1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.Run(ctx)
+
+	expected := map[string]time.Duration{
+		"a":         0,
+		"b":         30 * time.Second,
+		"no-resync": 0,
+		"c":         30 * time.Second,
+		"d":         45 * time.Second,
+	}
+	for _, c := range controllers {
+		if !c.ran {
+			t.Errorf("expected controller %q to run", c.name)
+		}
+		if c.initialDelay != expected[c.name] {
+			t.Errorf("expected controller %q initial delay %s, got %s", c.name, expected[c.name], c.initialDelay)
+		}
+	}
+}
+
+func TestManager_NoStagger(t *testing.T) {
+	c := &fakeController{name: "a", interval: time.Minute}
+	m := New().WithController(c, 1).WithController(&fakeController{name: "b", interval: time.Minute}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.Run(ctx)
+
+	if c.initialDelay != 0 {
+		t.Errorf("expected no initial delay without stagger, got %s", c.initialDelay)
+	}
+}
+
+type fakeScheduledController struct {
+	fakeController
+	schedules []framework.ScheduleStatus
+}
+
+func (f *fakeScheduledController) Schedules() []framework.ScheduleStatus {
+	return f.schedules
+}
+
+func TestManager_Schedules(t *testing.T) {
+	nextRun := time.Date(2023, 7, 1, 3, 0, 0, 0, time.UTC)
+	m := New().
+		WithController(&fakeScheduledController{fakeController: fakeController{name: "scheduled"}, schedules: []framework.ScheduleStatus{{Name: "@hourly", NextRun: nextRun}}}, 1).
+		WithController(&fakeScheduledController{fakeController: fakeController{name: "no-schedules"}}, 1).
+		WithController(&fakeController{name: "plain"}, 1)
+
+	schedules := m.Schedules()
+	if len(schedules) != 1 || len(schedules["scheduled"]) != 1 || !schedules["scheduled"][0].NextRun.Equal(nextRun) {
+		t.Errorf("unexpected schedules: %#v", schedules)
+	}
+}
+
+type fakePausableController struct {
+	fakeController
+	paused bool
+}
+
+func (f *fakePausableController) Pause() {
+	f.Lock()
+	defer f.Unlock()
+	f.paused = true
+}
+
+func (f *fakePausableController) Resume() {
+	f.Lock()
+	defer f.Unlock()
+	f.paused = false
+}
+
+func (f *fakePausableController) Paused() bool {
+	f.Lock()
+	defer f.Unlock()
+	return f.paused
+}
+
+func TestManager_PausedControllersConfigMap(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "paused"},
+		Data:       map[string]string{PausedControllersKey: "a, b\nunknown"},
+	})
+	a := &fakePausableController{fakeController: fakeController{name: "a"}}
+	b := &fakePausableController{fakeController: fakeController{name: "b"}}
+	c := &fakePausableController{fakeController: fakeController{name: "c"}}
+	m := New().WithController(a, 1).WithController(b, 1).WithController(c, 1).WithPausedControllersConfigMap(client, "ns", "paused")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	pausedControllers := func() string {
+		var paused []string
+		for _, status := range m.Status() {
+			if status.Paused {
+				paused = append(paused, status.Name)
+			}
+		}
+		return strings.Join(paused, ",")
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return pausedControllers() == "a,b", nil
+	}); err != nil {
+		t.Fatalf("expected a and b to be paused, got %q", pausedControllers())
+	}
+
+	if _, err := client.CoreV1().ConfigMaps("ns").Update(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "paused"},
+		Data:       map[string]string{PausedControllersKey: "c"},
+	}, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return pausedControllers() == "c", nil
+	}); err != nil {
+		t.Fatalf("expected only c to be paused, got %q", pausedControllers())
+	}
+
+	if err := client.CoreV1().ConfigMaps("ns").Delete(ctx, "paused", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return pausedControllers() == "", nil
+	}); err != nil {
+		t.Fatalf("expected all controllers to be resumed, got %q", pausedControllers())
+	}
+}
+
+func TestManager_StatusHandler(t *testing.T) {
+	a := &fakePausableController{fakeController: fakeController{name: "a"}, paused: true}
+	m := New().WithController(a, 1).WithController(&fakeController{name: "b"}, 1)
+
+	recorder := httptest.NewRecorder()
+	m.StatusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	var status []ControllerStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 2 || !status[0].Paused || status[1].Paused {
+		t.Errorf("unexpected status: %s", recorder.Body.String())
+	}
+}
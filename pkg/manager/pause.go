@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// PausedControllersKey is the key in the ConfigMap data listing the names of paused controllers.
+const PausedControllersKey = "pausedControllers"
+
+// pauseTrigger calls the setPaused function with the names of controllers that should be paused every time the list changes.
+// The trigger runs until the context is cancelled.
+type pauseTrigger func(ctx context.Context, setPaused func(names sets.Set[string]))
+
+// WithPausedControllersConfigMap pauses the controllers listed in the given ConfigMap.
+// The ConfigMap key PausedControllersKey holds the names of the controllers separated by commas or new lines.
+// The controllers that are removed from the list (or when the ConfigMap is deleted) are resumed.
+// Only controllers implementing framework.Pausable can be paused.
+func (m *Manager) WithPausedControllersConfigMap(client kubernetes.Interface, namespace, name string) *Manager {
+	m.pauseTriggers = append(m.pauseTriggers, func(ctx context.Context, setPaused func(names sets.Set[string])) {
+		informerFactory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(namespace), informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+		informer := informerFactory.Core().V1().ConfigMaps().Informer()
+		update := func(obj interface{}) {
+			configMap, ok := obj.(*corev1.ConfigMap)
+			if !ok || configMap.Name != name {
+				return
+			}
+			setPaused(parseControllerNames(configMap.Data[PausedControllersKey]))
+		}
+		if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    update,
+			UpdateFunc: func(_, obj interface{}) { update(obj) },
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if configMap, ok := obj.(*corev1.ConfigMap); ok && configMap.Name == name {
+					setPaused(sets.New[string]())
+				}
+			},
+		}); err != nil {
+			klog.Warningf("Failed to watch paused controllers configmap %s/%s: %v", namespace, name, err)
+			return
+		}
+		informerFactory.Start(ctx.Done())
+		<-ctx.Done()
+		informerFactory.Shutdown()
+	})
+	return m
+}
+
+// parseControllerNames parses the controller names separated by commas or new lines.
+func parseControllerNames(value string) sets.Set[string] {
+	names := sets.New[string]()
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names.Insert(name)
+		}
+	}
+	return names
+}
+
+// setPausedControllers pauses the pausable controllers with the given names and resumes the others.
+// The names are remembered, so the conditional controllers started later are paused as well.
+func (m *Manager) setPausedControllers(names sets.Set[string]) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pausedNames = names
+	for _, c := range m.controllers {
+		setPaused(c.controller, names)
+	}
+	for _, c := range m.conditionalControllers {
+		if c.controller != nil {
+			setPaused(c.controller, names)
+		}
+	}
+}
+
+func setPaused(controller framework.Controller, names sets.Set[string]) {
+	pausable, ok := controller.(framework.Pausable)
+	if !ok {
+		if names.Has(controller.Name()) {
+			klog.Warningf("Controller %s does not support pausing", controller.Name())
+		}
+		return
+	}
+	if names.Has(controller.Name()) {
+		pausable.Pause()
+	} else {
+		pausable.Resume()
+	}
+}
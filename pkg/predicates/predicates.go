@@ -0,0 +1,204 @@
+// Package predicates provides stock predicates for filtering informer events (see framework.Predicate).
+package predicates
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+// GenerationChanged accepts updates that increased the object metadata.generation, which for most resources means the
+// spec changed. The status and metadata updates are ignored. Create and delete events are accepted.
+func GenerationChanged() framework.Predicate {
+	return framework.PredicateFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			oldMeta, newMeta, ok := accessors(oldObj, newObj)
+			if !ok {
+				return true
+			}
+			return newMeta.GetGeneration() > oldMeta.GetGeneration()
+		},
+	}
+}
+
+// ResourceVersionChanged accepts updates that changed the object resourceVersion. This ignores the periodic informer resyncs
+// that deliver update events with the same object. Create and delete events are accepted.
+func ResourceVersionChanged() framework.Predicate {
+	return framework.PredicateFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			oldMeta, newMeta, ok := accessors(oldObj, newObj)
+			if !ok {
+				return true
+			}
+			return newMeta.GetResourceVersion() != oldMeta.GetResourceVersion()
+		},
+	}
+}
+
+// LabelsChanged accepts updates that changed the object labels. Create and delete events are accepted.
+func LabelsChanged() framework.Predicate {
+	return framework.PredicateFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			oldMeta, newMeta, ok := accessors(oldObj, newObj)
+			if !ok {
+				return true
+			}
+			return !labels.Equals(oldMeta.GetLabels(), newMeta.GetLabels())
+		},
+	}
+}
+
+// AnnotationChanged accepts updates that added, removed or changed the value of the given annotation.
+// Create and delete events are accepted.
+func AnnotationChanged(key string) framework.Predicate {
+	return framework.PredicateFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			oldMeta, newMeta, ok := accessors(oldObj, newObj)
+			if !ok {
+				return true
+			}
+			oldValue, oldExists := oldMeta.GetAnnotations()[key]
+			newValue, newExists := newMeta.GetAnnotations()[key]
+			return oldExists != newExists || oldValue != newValue
+		},
+	}
+}
+
+// LabelSelectorMatches accepts events of objects with labels matching the selector.
+// The update is accepted when either the old or the new object matches, so the controller observes the object that
+// stopped matching the selector.
+func LabelSelectorMatches(selector labels.Selector) framework.Predicate {
+	return ObjectMeta(func(obj metav1.Object) bool {
+		return selector.Matches(labels.Set(obj.GetLabels()))
+	})
+}
+
+// ObjectMeta returns predicate calling the function with the metadata of the object for all events. This works for both
+// the full objects and the metav1.PartialObjectMetadata observed by the metadata only informers. On update, the event is
+// accepted when either the old or the new object is accepted. The events of objects without metadata are rejected.
+func ObjectMeta(fn func(obj metav1.Object) bool) framework.Predicate {
+	accepts := func(obj interface{}) bool {
+		objMeta, ok := accessor(obj)
+		return ok && fn(objMeta)
+	}
+	return framework.PredicateFuncs{
+		CreateFunc: accepts,
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			return accepts(oldObj) || accepts(newObj)
+		},
+		DeleteFunc: accepts,
+	}
+}
+
+// ObjectMetaUpdate returns predicate calling the function with the metadata of the old and the new object on update.
+// Create and delete events are accepted. The updates of objects without metadata are accepted.
+func ObjectMetaUpdate(fn func(oldObj, newObj metav1.Object) bool) framework.Predicate {
+	return framework.PredicateFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			oldMeta, newMeta, ok := accessors(oldObj, newObj)
+			return !ok || fn(oldMeta, newMeta)
+		},
+	}
+}
+
+// And accepts the event when all the predicates accept it.
+func And(predicates ...framework.Predicate) framework.Predicate {
+	return framework.PredicateFuncs{
+		CreateFunc: func(obj interface{}) bool {
+			for _, p := range predicates {
+				if !p.Create(obj) {
+					return false
+				}
+			}
+			return true
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			for _, p := range predicates {
+				if !p.Update(oldObj, newObj) {
+					return false
+				}
+			}
+			return true
+		},
+		DeleteFunc: func(obj interface{}) bool {
+			for _, p := range predicates {
+				if !p.Delete(obj) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// Or accepts the event when any of the predicates accepts it.
+func Or(predicates ...framework.Predicate) framework.Predicate {
+	return framework.PredicateFuncs{
+		CreateFunc: func(obj interface{}) bool {
+			for _, p := range predicates {
+				if p.Create(obj) {
+					return true
+				}
+			}
+			return false
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			for _, p := range predicates {
+				if p.Update(oldObj, newObj) {
+					return true
+				}
+			}
+			return false
+		},
+		DeleteFunc: func(obj interface{}) bool {
+			for _, p := range predicates {
+				if p.Delete(obj) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// Not accepts the event when the predicate rejects it.
+func Not(predicate framework.Predicate) framework.Predicate {
+	return framework.PredicateFuncs{
+		CreateFunc: func(obj interface{}) bool {
+			return !predicate.Create(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) bool {
+			return !predicate.Update(oldObj, newObj)
+		},
+		DeleteFunc: func(obj interface{}) bool {
+			return !predicate.Delete(obj)
+		},
+	}
+}
+
+// accessor returns the object metadata, the deleted final state unknown tombstones are unwrapped.
+func accessor(obj interface{}) (metav1.Object, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, false
+	}
+	return objMeta, true
+}
+
+func accessors(oldObj, newObj interface{}) (metav1.Object, metav1.Object, bool) {
+	oldMeta, ok := accessor(oldObj)
+	if !ok {
+		return nil, nil, false
+	}
+	newMeta, ok := accessor(newObj)
+	if !ok {
+		return nil, nil, false
+	}
+	return oldMeta, newMeta, true
+}
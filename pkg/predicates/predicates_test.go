@@ -0,0 +1,110 @@
+package predicates
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+func secret(generation int64, resourceVersion string, labels, annotations map[string]string) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:            "test",
+		Namespace:       "test",
+		Generation:      generation,
+		ResourceVersion: resourceVersion,
+		Labels:          labels,
+		Annotations:     annotations,
+	}}
+}
+
+func TestUpdatePredicates(t *testing.T) {
+	base := secret(1, "1", map[string]string{"app": "foo"}, map[string]string{"a": "1"})
+	tests := []struct {
+		name           string
+		predicate      framework.Predicate
+		newObj         interface{}
+		expected       bool
+		expectedCreate bool
+	}{
+		{name: "generation increased", predicate: GenerationChanged(), newObj: secret(2, "2", nil, nil), expected: true, expectedCreate: true},
+		{name: "generation not changed", predicate: GenerationChanged(), newObj: secret(1, "2", nil, nil), expected: false, expectedCreate: true},
+		{name: "resource version changed", predicate: ResourceVersionChanged(), newObj: secret(1, "2", nil, nil), expected: true, expectedCreate: true},
+		{name: "resource version not changed", predicate: ResourceVersionChanged(), newObj: base, expected: false, expectedCreate: true},
+		{name: "labels changed", predicate: LabelsChanged(), newObj: secret(1, "2", map[string]string{"app": "bar"}, nil), expected: true, expectedCreate: true},
+		{name: "labels not changed", predicate: LabelsChanged(), newObj: secret(2, "2", map[string]string{"app": "foo"}, nil), expected: false, expectedCreate: true},
+		{name: "annotation changed", predicate: AnnotationChanged("a"), newObj: secret(1, "2", nil, map[string]string{"a": "2"}), expected: true, expectedCreate: true},
+		{name: "annotation removed", predicate: AnnotationChanged("a"), newObj: secret(1, "2", nil, nil), expected: true, expectedCreate: true},
+		{name: "other annotation changed", predicate: AnnotationChanged("a"), newObj: secret(1, "2", nil, map[string]string{"a": "1", "b": "1"}), expected: false, expectedCreate: true},
+		{name: "and", predicate: And(GenerationChanged(), LabelsChanged()), newObj: secret(2, "2", map[string]string{"app": "foo"}, nil), expected: false, expectedCreate: true},
+		{name: "or", predicate: Or(GenerationChanged(), LabelsChanged()), newObj: secret(2, "2", map[string]string{"app": "foo"}, nil), expected: true, expectedCreate: true},
+		{name: "not", predicate: Not(GenerationChanged()), newObj: secret(2, "2", nil, nil), expected: false, expectedCreate: false},
+		{name: "not an object", predicate: GenerationChanged(), newObj: "foo", expected: true, expectedCreate: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.predicate.Update(base, test.newObj); got != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+			if got := test.predicate.Create(base); got != test.expectedCreate {
+				t.Errorf("expected create to be %v, got %v", test.expectedCreate, got)
+			}
+		})
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	predicate := LabelSelectorMatches(labels.SelectorFromSet(labels.Set{"app": "foo"}))
+	matching := secret(1, "1", map[string]string{"app": "foo"}, nil)
+	other := secret(1, "2", map[string]string{"app": "bar"}, nil)
+
+	if !predicate.Create(matching) || predicate.Create(other) {
+		t.Errorf("expected only matching object to be accepted on create")
+	}
+	if !predicate.Update(matching, other) {
+		t.Errorf("expected update of the object that stopped matching to be accepted")
+	}
+	if predicate.Update(other, other) {
+		t.Errorf("expected update of not matching object to be rejected")
+	}
+	if !predicate.Delete(cache.DeletedFinalStateUnknown{Key: "test/test", Obj: matching}) {
+		t.Errorf("expected tombstone of matching object to be accepted")
+	}
+}
+
+func TestEventFilterFuncPredicate(t *testing.T) {
+	var nilFilter framework.EventFilterFunc
+	if !nilFilter.Create("foo") || !nilFilter.Update("foo", "bar") || !nilFilter.Delete("foo") {
+		t.Errorf("expected nil filter to accept all events")
+	}
+	filter := framework.EventFilterFunc(func(obj interface{}) bool { return obj == "foo" })
+	if !filter.Update("foo", "bar") || filter.Update("bar", "bar") {
+		t.Errorf("expected update to be accepted when old or new object passes the filter")
+	}
+}
+
+func TestObjectMetaPredicates(t *testing.T) {
+	partial := func(owner string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"owner": owner}}}
+	}
+	ownedByFoo := ObjectMeta(func(obj metav1.Object) bool {
+		return obj.GetLabels()["owner"] == "foo"
+	})
+	if !ownedByFoo.Create(partial("foo")) || ownedByFoo.Create(partial("bar")) || ownedByFoo.Create("not an object") {
+		t.Errorf("expected only objects owned by foo to be accepted")
+	}
+	if !ownedByFoo.Update(partial("foo"), partial("bar")) {
+		t.Errorf("expected update of object that stopped matching to be accepted")
+	}
+
+	ownerChanged := ObjectMetaUpdate(func(oldObj, newObj metav1.Object) bool {
+		return oldObj.GetLabels()["owner"] != newObj.GetLabels()["owner"]
+	})
+	if !ownerChanged.Update(partial("foo"), partial("bar")) || ownerChanged.Update(partial("foo"), partial("foo")) {
+		t.Errorf("expected only the owner change to be accepted")
+	}
+}
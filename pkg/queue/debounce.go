@@ -0,0 +1,183 @@
+package queue
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/utils/clock"
+)
+
+// defaultMaxWaitWindows is the number of debounce windows used as the maximum wait when the maximum wait is not set.
+const defaultMaxWaitWindows = 10
+
+var debouncedEventsMetric = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "controller",
+	Name:           "debounced_events_total",
+	Help:           "Number of informer events passed to the debouncer",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"name"})
+
+var coalescedEventsMetric = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "controller",
+	Name:           "coalesced_events_total",
+	Help:           "Number of informer events coalesced with the pending key by the debouncer and not enqueued on their own",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"name"})
+
+func init() {
+	legacyregistry.MustRegister(debouncedEventsMetric, coalescedEventsMetric)
+}
+
+// DebounceConfig configures the debouncer.
+type DebounceConfig struct {
+	// Name is used as the label of the debouncer metrics, usually the controller name.
+	Name string
+
+	// Window is the time the key has to be quiet (no new events) before it is added to the queue.
+	Window time.Duration
+
+	// MaxWait is the maximum time the key is delayed since the first event, even if the events keep coming.
+	// Defaults to ten times the Window.
+	MaxWait time.Duration
+
+	// Priority is the priority the keys are added with when the queue supports priorities (see AddWithPriority).
+	// When not set, the keys are added using Add().
+	Priority *int
+
+	// Clock allows to inject fake clock for testing.
+	Clock clock.Clock
+}
+
+// Debouncer delays adding the keys to the queue until the events for the key quiet down.
+// All events for the key received while the key is delayed are coalesced into a single add.
+type Debouncer struct {
+	queue  workqueue.Interface
+	config DebounceConfig
+
+	// pending are the keys waiting to be added to the queue
+	pending map[interface{}]*debouncedItem
+	lock    sync.Mutex
+
+	// stopCh is closed by ShutDown to stop the timers of the pending keys
+	stopCh       chan struct{}
+	shuttingDown bool
+}
+
+type debouncedItem struct {
+	// first is the time of the first event
+	first time.Time
+	// ready is the time the key should be added to the queue
+	ready time.Time
+}
+
+// NewDebouncer returns a debouncer adding the keys to the given queue.
+func NewDebouncer(queue workqueue.Interface, config DebounceConfig) *Debouncer {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	if config.MaxWait <= 0 {
+		config.MaxWait = defaultMaxWaitWindows * config.Window
+	}
+	if config.MaxWait < config.Window {
+		config.MaxWait = config.Window
+	}
+	return &Debouncer{
+		queue:   queue,
+		config:  config,
+		pending: map[interface{}]*debouncedItem{},
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Add schedules the item to be added to the queue when there are no more events for the window duration, but no later than
+// the maximum wait since the first event. The items added after ShutDown are dropped.
+func (d *Debouncer) Add(item interface{}) {
+	debouncedEventsMetric.WithLabelValues(d.config.Name).Inc()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.shuttingDown {
+		return
+	}
+	if d.config.Window <= 0 {
+		d.add(item)
+		return
+	}
+
+	now := d.config.Clock.Now()
+	if pending, ok := d.pending[item]; ok {
+		coalescedEventsMetric.WithLabelValues(d.config.Name).Inc()
+		pending.ready = earliest(now.Add(d.config.Window), pending.first.Add(d.config.MaxWait))
+		return
+	}
+	d.pending[item] = &debouncedItem{first: now, ready: now.Add(d.config.Window)}
+	d.fireAfter(item, d.config.Window)
+}
+
+// ShutDown stops the timers of the pending keys and drops the keys, they are not added to the queue.
+// The debouncer ignores all items added after the shutdown.
+func (d *Debouncer) ShutDown() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.shuttingDown {
+		return
+	}
+	d.shuttingDown = true
+	d.pending = map[interface{}]*debouncedItem{}
+	close(d.stopCh)
+}
+
+// Len returns the number of keys waiting to be added to the queue.
+func (d *Debouncer) Len() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return len(d.pending)
+}
+
+func (d *Debouncer) fireAfter(item interface{}, delay time.Duration) {
+	timer := d.config.Clock.NewTimer(delay)
+	go func() {
+		select {
+		case <-timer.C():
+			d.fire(item)
+		case <-d.stopCh:
+			timer.Stop()
+		}
+	}()
+}
+
+// fire adds the item to the queue, unless new events postponed it.
+func (d *Debouncer) fire(item interface{}) {
+	d.lock.Lock()
+	pending, ok := d.pending[item]
+	if !ok {
+		d.lock.Unlock()
+		return
+	}
+	if now := d.config.Clock.Now(); now.Before(pending.ready) {
+		d.fireAfter(item, pending.ready.Sub(now))
+		d.lock.Unlock()
+		return
+	}
+	delete(d.pending, item)
+	d.lock.Unlock()
+
+	d.add(item)
+}
+
+func (d *Debouncer) add(item interface{}) {
+	if d.config.Priority != nil {
+		AddWithPriority(d.queue, item, *d.config.Priority)
+		return
+	}
+	d.queue.Add(item)
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestDebouncer(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	q := workqueue.New()
+	defer q.ShutDown()
+	d := NewDebouncer(q, DebounceConfig{Name: "test", Window: time.Second, MaxWait: 3 * time.Second, Clock: fakeClock})
+
+	// waitFor waits until the debouncer timer is rescheduled (or fired) and verifies the queue length
+	waitFor := func(rescheduled bool, queued int) {
+		t.Helper()
+		if err := wait.PollImmediate(time.Millisecond, 5*time.Second, func() (bool, error) {
+			return fakeClock.HasWaiters() == rescheduled && q.Len() == queued, nil
+		}); err != nil {
+			t.Fatalf("expected %d queued items (rescheduled=%v), got %d items", queued, rescheduled, q.Len())
+		}
+	}
+
+	// burst of events is coalesced into single key
+	for i := 0; i < 5; i++ {
+		d.Add("a")
+	}
+	if d.Len() != 1 || q.Len() != 0 {
+		t.Fatalf("expected the key to be pending, got %d pending and %d queued", d.Len(), q.Len())
+	}
+
+	// the event received during the window postpones the key
+	fakeClock.Step(500 * time.Millisecond)
+	d.Add("a")
+	fakeClock.Step(600 * time.Millisecond)
+	waitFor(true, 0)
+	fakeClock.Step(400 * time.Millisecond)
+	waitFor(false, 1)
+	if d.Len() != 0 {
+		t.Errorf("expected no pending keys, got %d", d.Len())
+	}
+	item, _ := q.Get()
+	q.Done(item)
+
+	// the constant stream of events does not postpone the key for longer than max wait
+	d.Add("b")
+	for i := 0; i < 3; i++ {
+		fakeClock.Step(900 * time.Millisecond)
+		d.Add("b")
+		waitFor(true, 0)
+	}
+	fakeClock.Step(300 * time.Millisecond)
+	waitFor(false, 1)
+}
+
+func TestDebouncerWithoutWindow(t *testing.T) {
+	q := workqueue.New()
+	defer q.ShutDown()
+	d := NewDebouncer(q, DebounceConfig{})
+	d.Add("a")
+	if q.Len() != 1 {
+		t.Errorf("expected the key to be added immediately, got %d items", q.Len())
+	}
+}
+
+func TestDebouncerShutDown(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	q := workqueue.New()
+	defer q.ShutDown()
+	d := NewDebouncer(q, DebounceConfig{Name: "test", Window: time.Second, Clock: fakeClock})
+
+	d.Add("a")
+	if !fakeClock.HasWaiters() {
+		t.Fatalf("expected the timer of the pending key")
+	}
+	d.ShutDown()
+	// the timer goroutine stops the timer
+	if err := wait.PollImmediate(time.Millisecond, 5*time.Second, func() (bool, error) {
+		return !fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatalf("expected the timer to be stopped")
+	}
+
+	d.Add("b")
+	fakeClock.Step(time.Minute)
+	if d.Len() != 0 || q.Len() != 0 {
+		t.Errorf("expected no pending and no queued keys after shutdown, got %d pending and %d queued", d.Len(), q.Len())
+	}
+	// the shutdown can be called more than once
+	d.ShutDown()
+}
@@ -0,0 +1,196 @@
+package queue
+
+import (
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+)
+
+// TenantFunc returns the tenant (partition) of the queue item.
+type TenantFunc func(item interface{}) string
+
+// NamespaceTenant returns the namespace of "namespace/name" queue keys. Cluster scoped keys and items that are not strings
+// belong to the "" tenant.
+func NamespaceTenant(item interface{}) string {
+	key, ok := item.(string)
+	if !ok {
+		return ""
+	}
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return ""
+	}
+	return namespace
+}
+
+// FairQueueConfig configures the fair queue.
+type FairQueueConfig struct {
+	// Name of the queue, used for metrics of the delaying queue.
+	Name string
+
+	// TenantFunc partitions the items by tenant. Defaults to NamespaceTenant.
+	TenantFunc TenantFunc
+
+	// RateLimiter is used by AddRateLimited(). Defaults to workqueue.DefaultControllerRateLimiter().
+	RateLimiter workqueue.RateLimiter
+
+	// Clock allows to inject fake clock for testing.
+	Clock clock.WithTicker
+}
+
+// NewFairQueue returns a new rate limiting work queue that partitions the items by tenant and serves the tenants in
+// round-robin fashion. Items of the same tenant are processed in the order they were added.
+// This prevents a tenant with many items (eg. namespace with thousands of objects) from starving other tenants, the latency of
+// an item is bounded by the number of tenants with waiting items rather than the total number of waiting items.
+func NewFairQueue(config FairQueueConfig) workqueue.RateLimitingInterface {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	if config.RateLimiter == nil {
+		config.RateLimiter = workqueue.DefaultControllerRateLimiter()
+	}
+	if config.TenantFunc == nil {
+		config.TenantFunc = NamespaceTenant
+	}
+	q := &fairQueue{
+		tenantFunc: config.TenantFunc,
+		partitions: map[string][]interface{}{},
+		dirty:      map[interface{}]struct{}{},
+		processing: map[interface{}]struct{}{},
+		cond:       sync.NewCond(&sync.Mutex{}),
+	}
+	return workqueue.NewRateLimitingQueueWithConfig(config.RateLimiter, workqueue.RateLimitingQueueConfig{
+		Name:  config.Name,
+		Clock: config.Clock,
+		DelayingQueue: workqueue.NewDelayingQueueWithConfig(workqueue.DelayingQueueConfig{
+			Name:  config.Name,
+			Clock: config.Clock,
+			Queue: q,
+		}),
+	})
+}
+
+// fairQueue implements workqueue.Interface serving the tenants in round-robin fashion.
+type fairQueue struct {
+	tenantFunc TenantFunc
+
+	// partitions hold the waiting items per tenant
+	partitions map[string][]interface{}
+	// tenants is the round-robin order of tenants with waiting items
+	tenants []string
+	// length is the number of waiting items across all partitions
+	length int
+
+	// dirty are items that need to be processed, this include the waiting items and the items added while processing
+	dirty map[interface{}]struct{}
+	// processing are the items being processed by workers
+	processing map[interface{}]struct{}
+
+	shuttingDown bool
+	drain        bool
+	cond         *sync.Cond
+}
+
+var _ workqueue.Interface = &fairQueue{}
+
+func (q *fairQueue) Add(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	if _, ok := q.dirty[item]; ok {
+		return
+	}
+	q.dirty[item] = struct{}{}
+	if _, ok := q.processing[item]; ok {
+		// the item will be queued when the processing is done
+		return
+	}
+	q.pushLocked(item)
+}
+
+func (q *fairQueue) pushLocked(item interface{}) {
+	tenant := q.tenantFunc(item)
+	if len(q.partitions[tenant]) == 0 {
+		q.tenants = append(q.tenants, tenant)
+	}
+	q.partitions[tenant] = append(q.partitions[tenant], item)
+	q.length++
+	q.cond.Signal()
+}
+
+func (q *fairQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.length
+}
+
+func (q *fairQueue) Get() (interface{}, bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for q.length == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if q.length == 0 {
+		// we must be shutting down
+		return nil, true
+	}
+
+	// take the first item of the next tenant and move the tenant to the end of the round-robin order
+	tenant := q.tenants[0]
+	q.tenants = q.tenants[1:]
+	partition := q.partitions[tenant]
+	item := partition[0]
+	partition[0] = nil
+	if len(partition) > 1 {
+		q.partitions[tenant] = partition[1:]
+		q.tenants = append(q.tenants, tenant)
+	} else {
+		delete(q.partitions, tenant)
+	}
+	q.length--
+
+	delete(q.dirty, item)
+	q.processing[item] = struct{}{}
+	return item, false
+}
+
+func (q *fairQueue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	delete(q.processing, item)
+	if _, ok := q.dirty[item]; ok {
+		q.pushLocked(item)
+	}
+	if len(q.processing) == 0 {
+		q.cond.Broadcast()
+	}
+}
+
+func (q *fairQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = false
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *fairQueue) ShutDownWithDrain() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = true
+	q.shuttingDown = true
+	q.cond.Broadcast()
+	for len(q.processing) != 0 && q.drain {
+		q.cond.Wait()
+	}
+}
+
+func (q *fairQueue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFairQueue_RoundRobin(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{})
+	defer q.ShutDown()
+
+	for _, key := range []string{"big/1", "big/2", "big/3", "small/1", "other/1", "small/2", "cluster-scoped", "big/1"} {
+		q.Add(key)
+	}
+	if l := q.Len(); l != 7 {
+		t.Errorf("expected 7 items, got %d", l)
+	}
+	if order, expected := drain(t, q, 7), "big/1,small/1,other/1,cluster-scoped,big/2,small/2,big/3"; order != expected {
+		t.Errorf("expected order %q, got %q", expected, order)
+	}
+}
+
+func TestFairQueue_AddWhileProcessing(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{TenantFunc: func(interface{}) string { return "same" }})
+	defer q.ShutDown()
+
+	q.Add("a")
+	item, _ := q.Get()
+	q.Add("a")
+	q.Add("b")
+	if l := q.Len(); l != 1 {
+		t.Errorf("expected the processing item not to be queued, got %d items", l)
+	}
+	q.Done(item)
+	if order := drain(t, q, 2); order != "b,a" {
+		t.Errorf("expected the item added while processing to be queued after done, got %q", order)
+	}
+}
+
+// TestFairQueue_BoundedLatency verifies that a key of a small tenant is processed after at most one key of every other
+// tenant, no matter how many keys the big tenants have queued.
+func TestFairQueue_BoundedLatency(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{})
+	defer q.ShutDown()
+
+	bigTenants := 3
+	for i := 0; i < 1000; i++ {
+		for tenant := 0; tenant < bigTenants; tenant++ {
+			q.Add(fmt.Sprintf("big-%d/object-%d", tenant, i))
+		}
+	}
+
+	for round := 0; round < 10; round++ {
+		// process some keys of the big tenants, then a small tenant adds a key
+		drain(t, q, 17)
+		smallKey := fmt.Sprintf("small-%d/object", round)
+		q.Add(smallKey)
+
+		processed := 0
+		for {
+			item, _ := q.Get()
+			q.Done(item)
+			processed++
+			if item == smallKey {
+				break
+			}
+		}
+		if processed > bigTenants+1 {
+			t.Errorf("round %d: expected small tenant key to be processed within %d items, took %d (queue length %d)", round, bigTenants+1, processed, q.Len())
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package queue
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// PriorityLow is the priority used for bulk keys, eg. the keys added by periodic resync.
+	PriorityLow = -100
+	// PriorityNormal is the default priority of keys added without priority.
+	PriorityNormal = 0
+	// PriorityHigh is the priority for keys that should jump ahead of all other keys, eg. user facing changes.
+	PriorityHigh = 100
+)
+
+// defaultStarvationTimeout is the time after which a waiting item is served regardless of its priority.
+const defaultStarvationTimeout = time.Minute
+
+// PriorityFunc returns the priority of an item added to the queue without an explicit priority.
+type PriorityFunc func(item interface{}) int
+
+// PriorityInterface is a rate limiting work queue where items with higher priority are processed first.
+type PriorityInterface interface {
+	workqueue.RateLimitingInterface
+
+	// AddWithPriority adds the item with the given priority. If the item is already waiting in the queue with lower priority,
+	// its priority is raised. The priority is kept until the item is forgotten (see Forget()), so the item added again
+	// without the priority (eg. requeued via AddRateLimited()) keeps it.
+	AddWithPriority(item interface{}, priority int)
+
+	// AddAfterWithPriority adds the item with the given priority after the duration has passed.
+	AddAfterWithPriority(item interface{}, priority int, duration time.Duration)
+}
+
+// PriorityQueueConfig configures the priority queue.
+type PriorityQueueConfig struct {
+	// Name of the queue, used for metrics of the delaying queue.
+	Name string
+
+	// PriorityFunc returns the priority of items added via Add(), AddAfter() and AddRateLimited(), unless the items were
+	// added with the priority before and were not forgotten yet. If not set, PriorityNormal is used.
+	PriorityFunc PriorityFunc
+
+	// StarvationTimeout is the maximum time an item waits in the queue before it is served regardless of its priority.
+	// This ensures low priority items progress even when there is a constant stream of high priority items.
+	// Defaults to one minute.
+	StarvationTimeout time.Duration
+
+	// RateLimiter is used by AddRateLimited(). Defaults to workqueue.DefaultControllerRateLimiter().
+	RateLimiter workqueue.RateLimiter
+
+	// Clock allows to inject fake clock for testing.
+	Clock clock.WithTicker
+}
+
+// NewPriorityQueue returns a new rate limiting work queue processing the items by priority.
+// Items with the same priority are processed in the order they were added. Like the standard work queue, the item is never
+// processed by multiple workers at the same time and adding an item that is already waiting does not add it twice.
+func NewPriorityQueue(config PriorityQueueConfig) PriorityInterface {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	if config.RateLimiter == nil {
+		config.RateLimiter = workqueue.DefaultControllerRateLimiter()
+	}
+	if config.StarvationTimeout <= 0 {
+		config.StarvationTimeout = defaultStarvationTimeout
+	}
+	q := &priorityQueue{
+		clock:             config.Clock,
+		priorityFunc:      config.PriorityFunc,
+		starvationTimeout: config.StarvationTimeout,
+		queued:            map[interface{}]*priorityItem{},
+		dirty:             map[interface{}]struct{}{},
+		processing:        map[interface{}]struct{}{},
+		priorities:        map[interface{}]int{},
+		cond:              sync.NewCond(&sync.Mutex{}),
+	}
+	return &priorityRateLimitingQueue{
+		RateLimitingInterface: workqueue.NewRateLimitingQueueWithConfig(config.RateLimiter, workqueue.RateLimitingQueueConfig{
+			Name:  config.Name,
+			Clock: config.Clock,
+			DelayingQueue: workqueue.NewDelayingQueueWithConfig(workqueue.DelayingQueueConfig{
+				Name:  config.Name,
+				Clock: config.Clock,
+				Queue: q,
+			}),
+		}),
+		queue: q,
+	}
+}
+
+// AddWithPriority adds the item with the given priority if the queue supports priorities. Otherwise the item is added
+// using Add().
+func AddWithPriority(q workqueue.Interface, item interface{}, priority int) {
+	if priorityQueue, ok := q.(PriorityInterface); ok {
+		priorityQueue.AddWithPriority(item, priority)
+		return
+	}
+	q.Add(item)
+}
+
+// AddAfterWithPriority adds the item with the given priority after the duration if the queue supports priorities.
+// Otherwise the item is added using AddAfter().
+func AddAfterWithPriority(q workqueue.DelayingInterface, item interface{}, priority int, duration time.Duration) {
+	if priorityQueue, ok := q.(PriorityInterface); ok {
+		priorityQueue.AddAfterWithPriority(item, priority, duration)
+		return
+	}
+	q.AddAfter(item, duration)
+}
+
+type priorityRateLimitingQueue struct {
+	workqueue.RateLimitingInterface
+	queue *priorityQueue
+}
+
+func (q *priorityRateLimitingQueue) AddWithPriority(item interface{}, priority int) {
+	q.queue.AddWithPriority(item, priority)
+}
+
+func (q *priorityRateLimitingQueue) AddAfterWithPriority(item interface{}, priority int, duration time.Duration) {
+	if duration <= 0 {
+		q.queue.AddWithPriority(item, priority)
+		return
+	}
+	// the priority waits together with the item in the delaying queue
+	q.AddAfter(prioritizedItem{item: item, priority: priority}, duration)
+}
+
+// Forget forgets the item in the rate limiter and the last priority the item was added with.
+func (q *priorityRateLimitingQueue) Forget(item interface{}) {
+	q.RateLimitingInterface.Forget(item)
+	q.queue.forget(item)
+}
+
+// prioritizedItem is the item added via AddAfterWithPriority waiting in the delaying queue.
+type prioritizedItem struct {
+	item     interface{}
+	priority int
+}
+
+// priorityItem is an item waiting in the queue.
+type priorityItem struct {
+	item     interface{}
+	priority int
+	// sequence orders items with the same priority by the time they were added
+	sequence uint64
+	added    time.Time
+	// index is the position in the priority heap, -1 when the item is not in the heap
+	index int
+}
+
+// priorityQueue implements workqueue.Interface processing the items by priority.
+type priorityQueue struct {
+	clock             clock.Clock
+	priorityFunc      PriorityFunc
+	starvationTimeout time.Duration
+
+	// heap orders the waiting items by priority
+	heap priorityHeap
+	// fifo orders the waiting items by the time they were added, it can contain items already removed from heap
+	fifo []*priorityItem
+	// queued are the items waiting in the heap
+	queued map[interface{}]*priorityItem
+	// dirty are items that need to be processed, this include the queued items and the items added while processing
+	dirty map[interface{}]struct{}
+	// processing are the items being processed by workers
+	processing map[interface{}]struct{}
+	// priorities hold the last priority the items were added with until they are forgotten, so the items added again
+	// without the priority (eg. the failed items requeued via AddRateLimited) keep it
+	priorities map[interface{}]int
+	// pendingPriority hold the priority of dirty items that are being processed
+	pendingPriority map[interface{}]*priorityItem
+
+	sequence     uint64
+	shuttingDown bool
+	drain        bool
+	cond         *sync.Cond
+}
+
+var _ workqueue.Interface = &priorityQueue{}
+
+func (q *priorityQueue) Add(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if prioritized, ok := item.(prioritizedItem); ok {
+		q.priorities[prioritized.item] = prioritized.priority
+		q.addLocked(prioritized.item, prioritized.priority)
+		return
+	}
+	priority, ok := q.priorities[item]
+	switch {
+	case ok:
+	case q.priorityFunc != nil:
+		priority = q.priorityFunc(item)
+	default:
+		priority = PriorityNormal
+	}
+	q.addLocked(item, priority)
+}
+
+func (q *priorityQueue) AddWithPriority(item interface{}, priority int) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.priorities[item] = priority
+	q.addLocked(item, priority)
+}
+
+func (q *priorityQueue) forget(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	delete(q.priorities, item)
+}
+
+func (q *priorityQueue) addLocked(item interface{}, priority int) {
+	if q.shuttingDown {
+		return
+	}
+	if existing, ok := q.queued[item]; ok {
+		// raise the priority of the waiting item, keep its position among the items with the same priority
+		if priority > existing.priority {
+			existing.priority = priority
+			heap.Fix(&q.heap, existing.index)
+		}
+		return
+	}
+	if _, ok := q.dirty[item]; ok {
+		// the item is processing and was already added again
+		if pending := q.pendingPriority[item]; pending != nil && priority > pending.priority {
+			pending.priority = priority
+		}
+		return
+	}
+
+	q.sequence++
+	entry := &priorityItem{item: item, priority: priority, sequence: q.sequence, added: q.clock.Now()}
+	q.dirty[item] = struct{}{}
+	if _, ok := q.processing[item]; ok {
+		// the item will be queued when the processing is done
+		if q.pendingPriority == nil {
+			q.pendingPriority = map[interface{}]*priorityItem{}
+		}
+		q.pendingPriority[item] = entry
+		return
+	}
+	q.pushLocked(entry)
+}
+
+func (q *priorityQueue) pushLocked(entry *priorityItem) {
+	q.queued[entry.item] = entry
+	heap.Push(&q.heap, entry)
+	q.fifo = append(q.fifo, entry)
+	q.cond.Signal()
+}
+
+func (q *priorityQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.heap)
+}
+
+func (q *priorityQueue) Get() (interface{}, bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for len(q.heap) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.heap) == 0 {
+		// we must be shutting down
+		return nil, true
+	}
+
+	entry := q.starvingLocked()
+	if entry == nil {
+		entry = q.heap[0]
+	}
+	heap.Remove(&q.heap, entry.index)
+	delete(q.queued, entry.item)
+	delete(q.dirty, entry.item)
+	q.processing[entry.item] = struct{}{}
+	return entry.item, false
+}
+
+// starvingLocked returns the oldest item when it waited longer than the starvation timeout.
+func (q *priorityQueue) starvingLocked() *priorityItem {
+	// forget the items that were already removed from heap
+	for len(q.fifo) > 0 && q.fifo[0].index < 0 {
+		q.fifo[0] = nil
+		q.fifo = q.fifo[1:]
+	}
+	if len(q.fifo) == 0 {
+		return nil
+	}
+	if oldest := q.fifo[0]; !oldest.added.After(q.clock.Now().Add(-q.starvationTimeout)) {
+		return oldest
+	}
+	return nil
+}
+
+func (q *priorityQueue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	delete(q.processing, item)
+	if entry, ok := q.pendingPriority[item]; ok {
+		delete(q.pendingPriority, item)
+		q.pushLocked(entry)
+	}
+	if len(q.processing) == 0 {
+		q.cond.Broadcast()
+	}
+}
+
+func (q *priorityQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = false
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *priorityQueue) ShutDownWithDrain() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = true
+	q.shuttingDown = true
+	q.cond.Broadcast()
+	for len(q.processing) != 0 && q.drain {
+		q.cond.Wait()
+	}
+}
+
+func (q *priorityQueue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
+
+// priorityHeap orders the items by priority (higher first) and by the sequence (older first).
+type priorityHeap []*priorityItem
+
+func (h priorityHeap) Len() int { return len(h) }
+
+func (h priorityHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].sequence < h[j].sequence
+}
+
+func (h priorityHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *priorityHeap) Push(x interface{}) {
+	entry := x.(*priorityItem)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *priorityHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	entry.index = -1
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func drain(t *testing.T, q workqueue.Interface, count int) string {
+	t.Helper()
+	var items []string
+	for i := 0; i < count; i++ {
+		item, shutdown := q.Get()
+		if shutdown {
+			t.Fatal("unexpected shutdown")
+		}
+		items = append(items, item.(string))
+		q.Done(item)
+	}
+	return strings.Join(items, ",")
+}
+
+func TestPriorityQueue_Order(t *testing.T) {
+	q := NewPriorityQueue(PriorityQueueConfig{PriorityFunc: func(item interface{}) int {
+		if strings.HasPrefix(item.(string), "user-") {
+			return PriorityHigh
+		}
+		return PriorityNormal
+	}})
+	defer q.ShutDown()
+
+	q.AddWithPriority("resync-1", PriorityLow)
+	q.AddWithPriority("resync-2", PriorityLow)
+	q.Add("normal-1")
+	q.Add("user-1")
+	q.Add("normal-2")
+	q.Add("normal-1")                           // duplicate
+	q.AddWithPriority("resync-2", PriorityHigh) // raise priority
+
+	if l := q.Len(); l != 5 {
+		t.Errorf("expected 5 items, got %d", l)
+	}
+	if order, expected := drain(t, q, 5), "resync-2,user-1,normal-1,normal-2,resync-1"; order != expected {
+		t.Errorf("expected order %q, got %q", expected, order)
+	}
+}
+
+func TestPriorityQueue_AddWhileProcessing(t *testing.T) {
+	q := NewPriorityQueue(PriorityQueueConfig{})
+	defer q.ShutDown()
+
+	q.Add("a")
+	item, _ := q.Get()
+	q.AddWithPriority("a", PriorityLow)
+	q.AddWithPriority("a", PriorityHigh)
+	q.Add("b")
+	if l := q.Len(); l != 1 {
+		t.Errorf("expected the processing item not to be queued, got %d items", l)
+	}
+	q.Done(item)
+
+	if order := drain(t, q, 2); order != "a,b" {
+		t.Errorf("expected the item added while processing to keep the highest priority, got %q", order)
+	}
+}
+
+func TestPriorityQueue_Starvation(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	q := NewPriorityQueue(PriorityQueueConfig{StarvationTimeout: time.Minute, Clock: fakeClock})
+	defer q.ShutDown()
+
+	q.AddWithPriority("low", PriorityLow)
+	q.AddWithPriority("high-1", PriorityHigh)
+	if order := drain(t, q, 1); order != "high-1" {
+		t.Errorf("expected high priority item first, got %q", order)
+	}
+
+	fakeClock.Step(2 * time.Minute)
+	q.AddWithPriority("high-2", PriorityHigh)
+	if order := drain(t, q, 2); order != "low,high-2" {
+		t.Errorf("expected the starving item to be served first, got %q", order)
+	}
+}
+
+func TestPriorityQueue_AddAfterWithPriority(t *testing.T) {
+	q := NewPriorityQueue(PriorityQueueConfig{})
+	defer q.ShutDown()
+
+	q.AddAfterWithPriority("delayed", PriorityHigh, 10*time.Millisecond)
+	q.Add("normal")
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return q.Len() == 2, nil
+	}); err != nil {
+		t.Fatal("expected the delayed item to be added")
+	}
+	if order := drain(t, q, 2); order != "delayed,normal" {
+		t.Errorf("expected the delayed item to keep its priority, got %q", order)
+	}
+}
+
+func TestPriorityQueue_AddRateLimitedKeepsPriority(t *testing.T) {
+	q := NewPriorityQueue(PriorityQueueConfig{RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond)})
+	defer q.ShutDown()
+
+	q.AddWithPriority("high", PriorityHigh)
+	item, _ := q.Get()
+	q.Add("normal")
+	// the failed item is retried with its priority
+	q.AddRateLimited(item)
+	q.Done(item)
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return q.Len() == 2, nil
+	}); err != nil {
+		t.Fatal("expected the retried item to be added")
+	}
+	if order := drain(t, q, 2); order != "high,normal" {
+		t.Errorf("expected the retried item to keep its priority, got %q", order)
+	}
+
+	// the forgotten item is added with the default priority
+	q.Forget("high")
+	q.Add("normal")
+	q.Add("high")
+	if order := drain(t, q, 2); order != "normal,high" {
+		t.Errorf("expected the forgotten item to lose its priority, got %q", order)
+	}
+}
+
+func TestPriorityQueue_ShutDown(t *testing.T) {
+	q := NewPriorityQueue(PriorityQueueConfig{})
+	q.Add("a")
+	item, _ := q.Get()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, shutdown := q.Get(); !shutdown {
+			t.Error("expected shutdown")
+		}
+	}()
+	q.ShutDown()
+	<-done
+	q.Done(item)
+
+	q.Add("b")
+	if l := q.Len(); l != 0 {
+		t.Errorf("expected no items added after shutdown, got %d", l)
+	}
+}
+
+func TestAddWithPriorityFallback(t *testing.T) {
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	AddWithPriority(q, "a", PriorityHigh)
+	if l := q.Len(); l != 1 {
+		t.Errorf("expected the item to be added to the standard queue, got %d items", l)
+	}
+}
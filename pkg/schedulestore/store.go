@@ -0,0 +1,128 @@
+package schedulestore
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+type inMemoryStore struct {
+	lastRuns map[string]time.Time
+	sync.Mutex
+}
+
+var _ framework.ScheduleStore = &inMemoryStore{}
+
+// NewInMemoryStore returns schedule store that keeps the last runs in memory.
+// This store does not survive the process restart, it is useful for unit tests or when multiple controllers
+// share the same schedule store.
+func NewInMemoryStore() framework.ScheduleStore {
+	return &inMemoryStore{lastRuns: map[string]time.Time{}}
+}
+
+func (s *inMemoryStore) LastRun(_ context.Context, controllerName, scheduleName string) (time.Time, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.lastRuns[storeKey(controllerName, scheduleName)], nil
+}
+
+func (s *inMemoryStore) SetLastRun(_ context.Context, controllerName, scheduleName string, lastRun time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	s.lastRuns[storeKey(controllerName, scheduleName)] = lastRun
+	return nil
+}
+
+type configMapStore struct {
+	client    corev1client.ConfigMapsGetter
+	namespace string
+	name      string
+}
+
+var _ framework.ScheduleStore = &configMapStore{}
+
+// NewConfigMapStore returns schedule store that persists the last runs in the given ConfigMap.
+// The ConfigMap is created when it does not exist. Every schedule is stored under its own key with the last run in RFC3339 format.
+func NewConfigMapStore(client corev1client.ConfigMapsGetter, namespace, name string) framework.ScheduleStore {
+	return &configMapStore{client: client, namespace: namespace, name: name}
+}
+
+func (s *configMapStore) LastRun(ctx context.Context, controllerName, scheduleName string) (time.Time, error) {
+	configMap, err := s.client.ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	value, ok := configMap.Data[storeKey(controllerName, scheduleName)]
+	if !ok {
+		return time.Time{}, nil
+	}
+	lastRun, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last run of schedule %q in configmap %s/%s: %v", scheduleName, s.namespace, s.name, err)
+	}
+	return lastRun, nil
+}
+
+func (s *configMapStore) SetLastRun(ctx context.Context, controllerName, scheduleName string, lastRun time.Time) error {
+	key, value := storeKey(controllerName, scheduleName), lastRun.UTC().Format(time.RFC3339Nano)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := s.client.ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = s.client.ConfigMaps(s.namespace).Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: s.name},
+				Data:       map[string]string{key: value},
+			}, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// retry the update
+				return apierrors.NewConflict(corev1.Resource("configmaps"), s.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if configMap.Data[key] == value {
+			return nil
+		}
+		configMap = configMap.DeepCopy()
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[key] = value
+		_, err = s.client.ConfigMaps(s.namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// maxKeyPrefixLength keeps the ConfigMap keys within the 253 characters limit.
+const maxKeyPrefixLength = 200
+
+var invalidKeyCharacters = regexp.MustCompile(`[^-._a-zA-Z0-9]+`)
+
+// storeKey returns a valid ConfigMap key for the controller schedule.
+// The controller and schedule names might contain characters not allowed in the keys, so the invalid characters are replaced
+// and the hash of the original name is appended to keep the keys unique.
+func storeKey(controllerName, scheduleName string) string {
+	name := controllerName + "." + scheduleName
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	prefix := invalidKeyCharacters.ReplaceAllString(name, "_")
+	if len(prefix) > maxKeyPrefixLength {
+		prefix = prefix[:maxKeyPrefixLength]
+	}
+	return fmt.Sprintf("%s.%08x", prefix, hash.Sum32())
+}
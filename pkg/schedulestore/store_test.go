@@ -0,0 +1,70 @@
+package schedulestore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/mfojtik/controller-framework/pkg/framework"
+)
+
+func testStore(t *testing.T, store framework.ScheduleStore) {
+	ctx := context.TODO()
+	lastRun, err := store.LastRun(ctx, "controller", "0 3 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lastRun.IsZero() {
+		t.Errorf("expected no last run, got %s", lastRun)
+	}
+
+	expected := time.Date(2023, 7, 1, 3, 0, 0, 0, time.UTC)
+	if err := store.SetLastRun(ctx, "controller", "0 3 * * *", expected); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLastRun(ctx, "controller", "@hourly", expected.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if lastRun, err := store.LastRun(ctx, "controller", "0 3 * * *"); err != nil || !lastRun.Equal(expected) {
+		t.Errorf("expected last run %s, got %s (%v)", expected, lastRun, err)
+	}
+	if lastRun, err := store.LastRun(ctx, "other", "0 3 * * *"); err != nil || !lastRun.IsZero() {
+		t.Errorf("expected no last run for other controller, got %s (%v)", lastRun, err)
+	}
+}
+
+func TestInMemoryStore(t *testing.T) {
+	testStore(t, NewInMemoryStore())
+}
+
+func TestConfigMapStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	testStore(t, NewConfigMapStore(client.CoreV1(), "ns", "schedules"))
+
+	configMap, err := client.CoreV1().ConfigMaps("ns").Get(context.TODO(), "schedules", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configMap.Data) != 2 {
+		t.Errorf("expected two schedules stored, got %v", configMap.Data)
+	}
+	for key := range configMap.Data {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			t.Errorf("invalid configmap key %q: %v", key, errs)
+		}
+	}
+}
+
+func TestStoreKey(t *testing.T) {
+	if storeKey("a", "0 3 * * *") == storeKey("a", "0_3_____") {
+		t.Error("expected different keys for different schedules")
+	}
+	if key := storeKey(strings.Repeat("a", 300), "@hourly"); len(key) > 253 {
+		t.Errorf("expected key to be shorter than 253 characters, got %d", len(key))
+	}
+}